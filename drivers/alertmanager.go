@@ -0,0 +1,79 @@
+package drivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	PayloadFormatNone         = "none"
+	PayloadFormatAlertmanager = "alertmanager"
+
+	alertStatusFiring   = "firing"
+	alertStatusResolved = "resolved"
+
+	alertGroupExpiry = 24 * time.Hour
+)
+
+//AlertmanagerNotification is the part of the Alertmanager webhook payload used by the drivers
+type AlertmanagerNotification struct {
+	Status   string `json:"status"`
+	GroupKey string `json:"groupKey"`
+	Receiver string `json:"receiver"`
+}
+
+//ParseAlertmanagerNotification reads an Alertmanager webhook notification from a request body
+func ParseAlertmanagerNotification(payload []byte) (*AlertmanagerNotification, error) {
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("No Payload recevied from webhook")
+	}
+	notification := &AlertmanagerNotification{}
+	if err := json.Unmarshal(payload, notification); err != nil {
+		return nil, fmt.Errorf("Invalid Alertmanager payload: %v", err)
+	}
+	if notification.Status != alertStatusFiring && notification.Status != alertStatusResolved {
+		return nil, fmt.Errorf("Invalid Alertmanager status %q", notification.Status)
+	}
+	return notification, nil
+}
+
+type alertGroupState struct {
+	status  string
+	updated time.Time
+}
+
+//alertGroupTracker remembers the last handled status of every alert group, Alertmanager resends
+//a group notification on every repeat interval and these must not scale again
+type alertGroupTracker struct {
+	sync.Mutex
+	groups map[string]alertGroupState
+}
+
+var alertGroups = &alertGroupTracker{groups: map[string]alertGroupState{}}
+
+func (t *alertGroupTracker) isDuplicate(receiverID string, notification *AlertmanagerNotification) bool {
+	if notification.GroupKey == "" {
+		return false
+	}
+	t.Lock()
+	defer t.Unlock()
+	state, ok := t.groups[receiverID+"/"+notification.GroupKey]
+	return ok && state.status == notification.Status && time.Since(state.updated) < alertGroupExpiry
+}
+
+func (t *alertGroupTracker) handled(receiverID string, notification *AlertmanagerNotification) {
+	if notification.GroupKey == "" {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	now := time.Now()
+	for key, state := range t.groups {
+		if now.Sub(state.updated) >= alertGroupExpiry {
+			delete(t.groups, key)
+		}
+	}
+	t.groups[receiverID+"/"+notification.GroupKey] = alertGroupState{status: notification.Status, updated: now}
+}
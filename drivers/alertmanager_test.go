@@ -0,0 +1,109 @@
+package drivers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestParseAlertmanagerNotification(t *testing.T) {
+	tests := []struct {
+		payload  string
+		status   string
+		groupKey string
+		valid    bool
+	}{
+		{`{"version": "4", "status": "firing", "groupKey": "{}:{alertname=\"HighLoad\"}", "receiver": "webhook"}`,
+			alertStatusFiring, `{}:{alertname="HighLoad"}`, true},
+		{`{"version": "4", "status": "resolved", "groupKey": "g1", "receiver": "webhook"}`, alertStatusResolved, "g1", true},
+		{``, "", "", false},
+		{`not json`, "", "", false},
+		{`{"status": "pending", "groupKey": "g1"}`, "", "", false},
+		{`{"groupKey": "g1"}`, "", "", false},
+	}
+
+	for _, test := range tests {
+		notification, err := ParseAlertmanagerNotification([]byte(test.payload))
+		if !test.valid {
+			if err == nil {
+				t.Fatalf("Expected error for payload %s", test.payload)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error %v for payload %s", err, test.payload)
+		}
+		if notification.Status != test.status || notification.GroupKey != test.groupKey {
+			t.Fatalf("Expected %s %s, got %#v", test.status, test.groupKey, notification)
+		}
+	}
+}
+
+func TestScaleServiceAlertmanager(t *testing.T) {
+	notification := func(status string) string {
+		return fmt.Sprintf(`{"version": "4", "status": "%s", "groupKey": "{}:{alertname=\"HighLoad\"}", "receiver": "webhook"}`, status)
+	}
+
+	steps := []struct {
+		reverseOnResolved bool
+		payload           string
+		expectedScale     int64
+		expectedMessage   string
+	}{
+		{false, notification(alertStatusFiring), 3, ""},
+		//Alertmanager repeats a firing group on every repeat interval
+		{false, notification(alertStatusFiring), 3, `Alert group {}:{alertname="HighLoad"} was already handled as firing, skipping scale`},
+		{false, notification(alertStatusResolved), 3, "Alert resolved, skipping scale"},
+		{false, notification(alertStatusResolved), 3, `Alert group {}:{alertname="HighLoad"} was already handled as resolved, skipping scale`},
+		{true, notification(alertStatusFiring), 4, ""},
+		{true, notification(alertStatusResolved), 3, ""},
+		{true, notification(alertStatusResolved), 3, `Alert group {}:{alertname="HighLoad"} was already handled as resolved, skipping scale`},
+	}
+
+	apiClient, services := newFakeClient(client.Service{Resource: client.Resource{Id: "1s1"}, Scale: 2})
+	driver := &ScaleServiceDriver{}
+	for i, step := range steps {
+		config := configMap(t, model.ScaleService{
+			ServiceID:         "1s1",
+			ScaleAction:       scaleActionUp,
+			ScaleChange:       1,
+			Min:               1,
+			Max:               10,
+			PayloadFormat:     PayloadFormatAlertmanager,
+			ReverseOnResolved: step.reverseOnResolved,
+		})
+		request, job := jobRequest(t, "1wr-alertmanager", step.payload)
+		code, err := driver.Execute(config, apiClient, request)
+		if code != http.StatusOK || err != nil {
+			t.Fatalf("Step %d: unexpected %d %v", i, code, err)
+		}
+		if scale := services.scale("1s1"); scale != step.expectedScale {
+			t.Fatalf("Step %d: expected scale %d, got %d", i, step.expectedScale, scale)
+		}
+		if message := job.Resource().Message; message != step.expectedMessage {
+			t.Fatalf("Step %d: expected message %q, got %q", i, step.expectedMessage, message)
+		}
+	}
+}
+
+func TestScaleServiceAlertmanagerInvalidPayload(t *testing.T) {
+	apiClient, services := newFakeClient(client.Service{Resource: client.Resource{Id: "1s1"}, Scale: 2})
+	config := configMap(t, model.ScaleService{
+		ServiceID:     "1s1",
+		ScaleAction:   scaleActionUp,
+		ScaleChange:   1,
+		Min:           1,
+		Max:           10,
+		PayloadFormat: PayloadFormatAlertmanager,
+	})
+	request, _ := jobRequest(t, "1wr-alertmanager-invalid", `{"text": "not an alert"}`)
+	if code, err := (&ScaleServiceDriver{}).Execute(config, apiClient, request); code != http.StatusBadRequest || err == nil {
+		t.Fatalf("Expected bad request, got %d %v", code, err)
+	}
+	if len(services.updates) != 0 {
+		t.Fatalf("Invalid payload should not scale, got updates %v", services.updates)
+	}
+}
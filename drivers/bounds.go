@@ -0,0 +1,64 @@
+package drivers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	BoundsBehaviorReject = "reject"
+	BoundsBehaviorClamp  = "clamp"
+)
+
+//validateBoundsBehavior checks the boundsBehavior option shared by the scaling drivers
+func validateBoundsBehavior(behavior string) (int, error) {
+	if behavior != "" && behavior != BoundsBehaviorReject && behavior != BoundsBehaviorClamp {
+		return http.StatusBadRequest, fmt.Errorf("Invalid boundsBehavior %v", behavior)
+	}
+	return http.StatusOK, nil
+}
+
+//boundScale keeps target within min and max, rejecting it unless behavior is clamp.
+//A clamped change never moves the scale in the opposite direction of the requested one
+func boundScale(current int64, target int64, min int64, max int64, behavior string) (int64, error) {
+	if target > max {
+		if behavior != BoundsBehaviorClamp {
+			return 0, fmt.Errorf("Cannot scale above provided max scale value")
+		}
+		if max < current {
+			return current, nil
+		}
+		return max, nil
+	}
+	if target < min {
+		if behavior != BoundsBehaviorClamp {
+			return 0, fmt.Errorf("Cannot scale below provided min scale value")
+		}
+		if min > current {
+			return current, nil
+		}
+		return min, nil
+	}
+	return target, nil
+}
+
+//scaleChange describes an applied scale operation, to differs from requested when it was clamped
+type scaleChange struct {
+	from      int64
+	requested int64
+	to        int64
+}
+
+func (c scaleChange) String() string {
+	applied := c.to - c.from
+	if c.requested == c.from {
+		return fmt.Sprintf("Scale already at %d", c.from)
+	}
+	if c.to == c.from {
+		return fmt.Sprintf("Scale left at %d, requested change %+d is out of bounds", c.from, c.requested-c.from)
+	}
+	if c.to != c.requested {
+		return fmt.Sprintf("Scaled from %d to %d, applied change %+d of requested %+d", c.from, c.to, applied, c.requested-c.from)
+	}
+	return fmt.Sprintf("Scaled from %d to %d", c.from, c.to)
+}
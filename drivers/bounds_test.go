@@ -0,0 +1,97 @@
+package drivers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestBoundScale(t *testing.T) {
+	tests := []struct {
+		current  int64
+		target   int64
+		min      int64
+		max      int64
+		behavior string
+		expected int64
+		valid    bool
+	}{
+		{3, 5, 1, 10, BoundsBehaviorReject, 5, true},
+		{3, 12, 1, 10, BoundsBehaviorReject, 0, false},
+		{3, 12, 1, 10, "", 0, false},
+		{3, 0, 1, 10, BoundsBehaviorReject, 0, false},
+		{3, 12, 1, 10, BoundsBehaviorClamp, 10, true},
+		{3, 0, 1, 10, BoundsBehaviorClamp, 1, true},
+		//a service already outside the bounds is never moved against the requested direction
+		{12, 14, 1, 10, BoundsBehaviorClamp, 12, true},
+		{0, -2, 1, 10, BoundsBehaviorClamp, 0, true},
+		{10, 10, 1, 10, BoundsBehaviorReject, 10, true},
+	}
+
+	for _, test := range tests {
+		scale, err := boundScale(test.current, test.target, test.min, test.max, test.behavior)
+		if !test.valid {
+			if err == nil {
+				t.Fatalf("Expected %d to %d within [%d, %d] to be rejected, got %d", test.current, test.target, test.min, test.max, scale)
+			}
+			continue
+		}
+		if err != nil || scale != test.expected {
+			t.Fatalf("Expected %d to %d within [%d, %d] with %q to give %d, got %d %v",
+				test.current, test.target, test.min, test.max, test.behavior, test.expected, scale, err)
+		}
+	}
+}
+
+func TestScaleChangeString(t *testing.T) {
+	tests := []struct {
+		change   scaleChange
+		expected string
+	}{
+		{scaleChange{from: 2, requested: 4, to: 4}, "Scaled from 2 to 4"},
+		{scaleChange{from: 8, requested: 12, to: 10}, "Scaled from 8 to 10, applied change +2 of requested +4"},
+		{scaleChange{from: 10, requested: 12, to: 10}, "Scale left at 10, requested change +2 is out of bounds"},
+		{scaleChange{from: 5, requested: 5, to: 5}, "Scale already at 5"},
+	}
+
+	for _, test := range tests {
+		if message := test.change.String(); message != test.expected {
+			t.Fatalf("Expected %q, got %q", test.expected, message)
+		}
+	}
+}
+
+func TestScaleTargetBoundsBehavior(t *testing.T) {
+	tests := []struct {
+		action   string
+		behavior string
+		current  int64
+		change   int64
+		expected int64
+		valid    bool
+	}{
+		{scaleActionUp, BoundsBehaviorReject, 8, 4, 0, false},
+		{scaleActionUp, BoundsBehaviorClamp, 8, 4, 10, true},
+		{scaleActionDown, BoundsBehaviorReject, 3, 4, 0, false},
+		{scaleActionDown, BoundsBehaviorClamp, 3, 4, 2, true},
+		{scaleActionUp, BoundsBehaviorReject, 5, 2, 7, true},
+		//only the bound in the direction of the change applies
+		{scaleActionDown, BoundsBehaviorReject, 12, 1, 11, true},
+		{scaleActionUp, BoundsBehaviorReject, 1, 1, 2, true},
+	}
+
+	for i, test := range tests {
+		config := &model.ScaleService{ScaleChange: test.change, Min: 2, Max: 10, BoundsBehavior: test.behavior}
+		change, code, err := scaleTarget(config, test.action, test.current, nil)
+		if !test.valid {
+			if code != http.StatusBadRequest || err == nil {
+				t.Fatalf("Case %d: expected bad request, got %d %v", i, code, err)
+			}
+			continue
+		}
+		if err != nil || change.to != test.expected {
+			t.Fatalf("Case %d: expected scale %d, got %#v %v", i, test.expected, change, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package drivers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/rancher/go-rancher/v2"
+)
+
+const (
+	skippedCoolingDown = "skipped: cooling down"
+	lastScaledField    = "lastScaled"
+)
+
+//cooldownKind is the kind of the genericObjects recording the last scale of a receiver.
+//Keeping them apart from the receiver means recording a scale never races with updates of the receiver itself
+const cooldownKind = "webhookReceiverCooldown"
+
+//cooldownTracker records the last successful scale of every receiver in a genericObject of its own,
+//so cooldowns survive restarts and are shared by every instance of the service.
+//The in-memory copy is only used when the cooldown can't be read or saved
+type cooldownTracker struct {
+	sync.Mutex
+	lastScaled map[string]time.Time
+	reserved   map[string]bool
+}
+
+var cooldowns = &cooldownTracker{lastScaled: map[string]time.Time{}, reserved: map[string]bool{}}
+
+//reserve returns how long the receiver is still cooling down, zero when it can scale.
+//A receiver that can scale is held until release, concurrent executions of it are skipped as cooling down
+func (c *cooldownTracker) reserve(apiClient *client.RancherClient, receiverID string, cooldownSeconds int64) time.Duration {
+	if receiverID == "" || cooldownSeconds <= 0 {
+		return 0
+	}
+	cooldown := time.Duration(cooldownSeconds) * time.Second
+	c.Lock()
+	defer c.Unlock()
+	if c.reserved[receiverID] {
+		return cooldown
+	}
+	lastScaled, ok := c.lastScaled[receiverID]
+	if persisted, err := persistedLastScaled(apiClient, receiverID); err != nil {
+		log.Warnf("Couldn't read last scale of receiver %s, using in-memory cooldown: %v", receiverID, err)
+	} else if persisted.After(lastScaled) {
+		lastScaled, ok = persisted, true
+	}
+	if ok {
+		if remaining := lastScaled.Add(cooldown).Sub(time.Now()); remaining > 0 {
+			return remaining
+		}
+	}
+	c.reserved[receiverID] = true
+	return 0
+}
+
+//release ends the reservation of the receiver, a scale starts its cooldown
+func (c *cooldownTracker) release(apiClient *client.RancherClient, receiverID string, scaled bool) {
+	if receiverID == "" {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	delete(c.reserved, receiverID)
+	if !scaled {
+		return
+	}
+	now := time.Now()
+	c.lastScaled[receiverID] = now
+	if err := persistLastScaled(apiClient, receiverID, now); err != nil {
+		log.Warnf("Couldn't save last scale of receiver %s: %v", receiverID, err)
+	}
+}
+
+//DeleteCooldown deletes the recorded last scale of a deleted receiver
+func DeleteCooldown(apiClient *client.RancherClient, receiverID string) error {
+	cooldown, err := findCooldown(apiClient, receiverID)
+	if err != nil || cooldown == nil {
+		return err
+	}
+	return apiClient.GenericObject.Delete(cooldown)
+}
+
+func findCooldown(apiClient *client.RancherClient, receiverID string) (*client.GenericObject, error) {
+	filters := make(map[string]interface{})
+	filters["kind"] = cooldownKind
+	filters["name"] = receiverID
+	objects, err := apiClient.GenericObject.List(&client.ListOpts{
+		Filters: filters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error %v listing cooldowns", err)
+	}
+	for i, obj := range objects.Data {
+		if obj.Kind == cooldownKind {
+			return &objects.Data[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func persistedLastScaled(apiClient *client.RancherClient, receiverID string) (time.Time, error) {
+	cooldown, err := findCooldown(apiClient, receiverID)
+	if err != nil || cooldown == nil {
+		return time.Time{}, err
+	}
+	lastScaled, _ := cooldown.ResourceData[lastScaledField].(string)
+	if lastScaled == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, lastScaled)
+}
+
+func persistLastScaled(apiClient *client.RancherClient, receiverID string, lastScaled time.Time) error {
+	resourceData := map[string]interface{}{
+		"receiverId":    receiverID,
+		lastScaledField: lastScaled.UTC().Format(time.RFC3339),
+	}
+	cooldown, err := findCooldown(apiClient, receiverID)
+	if err != nil {
+		return err
+	}
+	if cooldown == nil {
+		_, err = apiClient.GenericObject.Create(&client.GenericObject{
+			Name:         receiverID,
+			Kind:         cooldownKind,
+			ResourceData: resourceData,
+		})
+		return err
+	}
+	_, err = apiClient.GenericObject.Update(cooldown, &client.GenericObject{
+		ResourceData: resourceData,
+	})
+	return err
+}
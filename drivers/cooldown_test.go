@@ -0,0 +1,166 @@
+package drivers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestScaleServiceCooldown(t *testing.T) {
+	apiClient, services := newFakeClient(
+		client.Service{Resource: client.Resource{Id: "1s1"}, Scale: 2},
+		client.Service{Resource: client.Resource{Id: "1s2"}, Scale: 10},
+	)
+	scaleUp := func(serviceID string, receiverID string) *Job {
+		config := configMap(t, model.ScaleService{
+			ServiceID:       serviceID,
+			ScaleAction:     scaleActionUp,
+			ScaleChange:     1,
+			Min:             1,
+			Max:             10,
+			BoundsBehavior:  BoundsBehaviorClamp,
+			CooldownSeconds: 60,
+		})
+		request, job := jobRequest(t, receiverID, "")
+		if code, err := (&ScaleServiceDriver{}).Execute(config, apiClient, request); code != http.StatusOK || err != nil {
+			t.Fatalf("Unexpected %d %v", code, err)
+		}
+		return job
+	}
+
+	cooling := receiver(apiClient, "1go-cooldown")
+	if job := scaleUp("1s1", cooling); job.Resource().Message == skippedCoolingDown || services.scale("1s1") != 3 {
+		t.Fatalf("First scale should not cool down, got scale %d", services.scale("1s1"))
+	}
+	if job := scaleUp("1s1", cooling); job.Resource().Message != skippedCoolingDown || services.scale("1s1") != 3 {
+		t.Fatalf("Second scale should cool down, got %q and scale %d", job.Resource().Message, services.scale("1s1"))
+	}
+
+	//the cooldown is read back from its own genericObject, not only from this process, and leaves the receiver alone
+	cooldowns.Lock()
+	delete(cooldowns.lastScaled, cooling)
+	cooldowns.Unlock()
+	if job := scaleUp("1s1", cooling); job.Resource().Message != skippedCoolingDown {
+		t.Fatalf("Expected cooldown persisted, got %q", job.Resource().Message)
+	}
+	if persisted, _ := apiClient.GenericObject.ById(cooling); len(persisted.ResourceData) != 0 {
+		t.Fatalf("Receiver should not be updated by a cooldown, got %v", persisted.ResourceData)
+	}
+
+	//a clamped no-op doesn't start a cooldown
+	clamped := receiver(apiClient, "1go-clamped")
+	scaleUp("1s2", clamped)
+	if cooldown, _ := findCooldown(apiClient, clamped); cooldown != nil {
+		t.Fatalf("Clamped no-op should not record a cooldown, got %v", cooldown.ResourceData)
+	}
+	if job := scaleUp("1s2", clamped); job.Resource().Message == skippedCoolingDown {
+		t.Fatal("Receiver should not cool down after a no-op")
+	}
+}
+
+func TestCooldownRemaining(t *testing.T) {
+	apiClient, _ := newFakeClient()
+	id := receiver(apiClient, "1go-remaining")
+	tracker := &cooldownTracker{lastScaled: map[string]time.Time{}, reserved: map[string]bool{}}
+
+	tests := []struct {
+		lastScaled      string
+		cooldownSeconds int64
+		cooling         bool
+	}{
+		{"", 60, false},
+		{time.Now().Add(-30 * time.Second).UTC().Format(time.RFC3339), 60, true},
+		{time.Now().Add(-90 * time.Second).UTC().Format(time.RFC3339), 60, false},
+		{time.Now().UTC().Format(time.RFC3339), 0, false},
+		{"yesterday", 60, false},
+	}
+
+	for _, test := range tests {
+		if err := DeleteCooldown(apiClient, id); err != nil {
+			t.Fatal(err)
+		}
+		apiClient.GenericObject.Create(&client.GenericObject{
+			Name:         id,
+			Kind:         cooldownKind,
+			ResourceData: map[string]interface{}{lastScaledField: test.lastScaled},
+		})
+		remaining := tracker.reserve(apiClient, id, test.cooldownSeconds)
+		if (remaining > 0) != test.cooling {
+			t.Fatalf("Last scaled %q with cooldown %d: expected cooling %v, got %v", test.lastScaled, test.cooldownSeconds, test.cooling, remaining)
+		}
+		if remaining == 0 {
+			tracker.release(apiClient, id, false)
+		}
+	}
+}
+
+func TestCooldownReserve(t *testing.T) {
+	apiClient, _ := newFakeClient()
+	id := receiver(apiClient, "1go-reserve")
+	tracker := &cooldownTracker{lastScaled: map[string]time.Time{}, reserved: map[string]bool{}}
+
+	if remaining := tracker.reserve(apiClient, id, 60); remaining != 0 {
+		t.Fatalf("First execution should not cool down, got %v", remaining)
+	}
+	//a concurrent execution can't scale while the first one holds the receiver
+	if remaining := tracker.reserve(apiClient, id, 60); remaining == 0 {
+		t.Fatal("Execution during a reservation should cool down")
+	}
+	tracker.release(apiClient, id, false)
+	if remaining := tracker.reserve(apiClient, id, 60); remaining != 0 {
+		t.Fatalf("Released no-op should not cool down, got %v", remaining)
+	}
+	tracker.release(apiClient, id, true)
+	if remaining := tracker.reserve(apiClient, id, 60); remaining == 0 {
+		t.Fatal("Execution after a scale should cool down")
+	}
+
+	if err := DeleteCooldown(apiClient, id); err != nil {
+		t.Fatal(err)
+	}
+	if cooldown, _ := findCooldown(apiClient, id); cooldown != nil {
+		t.Fatalf("Cooldown not deleted: %#v", cooldown)
+	}
+}
+
+func TestScaleDownReportsChange(t *testing.T) {
+	apiClient, _ := newFakeClient()
+	hosts := []client.Host{
+		{Resource: client.Resource{Id: "1h3"}, State: "active"},
+		{Resource: client.Resource{Id: "1h2"}, State: "active"},
+		{Resource: client.Resource{Id: "1h1"}, State: "active"},
+	}
+
+	tests := []struct {
+		amount  int64
+		min     int64
+		changed bool
+		deleted []string
+	}{
+		{1, 1, true, []string{"1h3"}},
+		{0, 1, false, nil},
+		{5, 3, false, nil},
+	}
+
+	for _, test := range tests {
+		events := &fakeHostEvents{}
+		apiClient.ExternalHostEvent = events
+		job := NewJob("1j1", "1a1", "1go1", "scaleHost")
+		config := &model.ScaleHost{
+			Amount:         test.amount,
+			Min:            test.min,
+			DeleteOption:   "mostRecent",
+			BoundsBehavior: BoundsBehaviorClamp,
+		}
+		changed, code, err := scaleDown(hosts, config, apiClient, job)
+		if code != http.StatusOK || err != nil {
+			t.Fatalf("Amount %d: unexpected %d %v", test.amount, code, err)
+		}
+		if changed != test.changed || len(events.deleted) != len(test.deleted) {
+			t.Fatalf("Amount %d: expected changed %v deleting %v, got %v deleting %v", test.amount, test.changed, test.deleted, changed, events.deleted)
+		}
+	}
+}
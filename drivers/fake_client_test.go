@@ -0,0 +1,287 @@
+package drivers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/rancher/go-rancher/v2"
+)
+
+//fakeServices keeps services in memory for driver tests, scale updates and actions are applied immediately
+type fakeServices struct {
+	client.ServiceOperations
+	sync.Mutex
+	services map[string]*client.Service
+	updates  []string
+	actions  []string
+	restarts map[string]client.RollingRestartStrategy
+	failing  map[string]bool
+	stuck    map[string]bool
+}
+
+func newFakeClient(services ...client.Service) (*client.RancherClient, *fakeServices) {
+	fake := &fakeServices{services: map[string]*client.Service{}, restarts: map[string]client.RollingRestartStrategy{},
+		failing: map[string]bool{}, stuck: map[string]bool{}}
+	for i := range services {
+		service := services[i]
+		if service.Kind == "" {
+			service.Kind = "service"
+		}
+		if service.State == "" {
+			service.State = "active"
+		}
+		fake.services[service.Id] = &service
+	}
+	return &client.RancherClient{
+		RancherBaseClient: &fakeBaseClient{services: fake, transitioning: map[string]bool{}},
+		Service:           fake,
+		GenericObject:     &fakeGenericObjects{objects: map[string]*client.GenericObject{}},
+		ExternalHostEvent: &fakeHostEvents{},
+	}, fake
+}
+
+func (f *fakeServices) ById(id string) (*client.Service, error) {
+	f.Lock()
+	defer f.Unlock()
+	service, ok := f.services[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *service
+	return &copied, nil
+}
+
+func (f *fakeServices) List(opts *client.ListOpts) (*client.ServiceCollection, error) {
+	f.Lock()
+	defer f.Unlock()
+	ids := []string{}
+	for id := range f.services {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	services := []client.Service{}
+	for _, id := range ids {
+		services = append(services, *f.services[id])
+	}
+	return &client.ServiceCollection{Data: services}, nil
+}
+
+func (f *fakeServices) Update(existing *client.Service, updates interface{}) (*client.Service, error) {
+	f.Lock()
+	defer f.Unlock()
+	service, ok := f.services[existing.Id]
+	if !ok {
+		return nil, fmt.Errorf("Service %s doesn't exist", existing.Id)
+	}
+	update, ok := updates.(client.Service)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected update %#v", updates)
+	}
+	service.Scale = update.Scale
+	service.CurrentScale = update.CurrentScale
+	f.updates = append(f.updates, existing.Id)
+	copied := *service
+	return &copied, nil
+}
+
+//action records name for the service and sets the state the service ends up in
+func (f *fakeServices) action(name string, existing *client.Service, state string) (*client.Service, error) {
+	f.Lock()
+	defer f.Unlock()
+	service, ok := f.services[existing.Id]
+	if !ok {
+		return nil, fmt.Errorf("Service %s doesn't exist", existing.Id)
+	}
+	f.actions = append(f.actions, name+" "+existing.Id)
+	if f.failing[existing.Id] {
+		return nil, fmt.Errorf("%s of %s failed", name, existing.Id)
+	}
+	service.State = state
+	copied := *service
+	return &copied, nil
+}
+
+func (f *fakeServices) ActionRestart(existing *client.Service, input *client.ServiceRestart) (*client.Service, error) {
+	f.Lock()
+	f.restarts[existing.Id] = input.RollingRestartStrategy
+	f.Unlock()
+	return f.action("restart", existing, "active")
+}
+
+//ActionUpgrade leaves stuck services upgrading, they only settle once their upgrade is canceled
+func (f *fakeServices) ActionUpgrade(existing *client.Service, input *client.ServiceUpgrade) (*client.Service, error) {
+	f.Lock()
+	stuck := f.stuck[existing.Id]
+	f.Unlock()
+	if stuck {
+		return f.action("upgrade", existing, "upgrading")
+	}
+	return f.action("upgrade", existing, "upgraded")
+}
+
+func (f *fakeServices) ActionCancelupgrade(existing *client.Service) (*client.Service, error) {
+	return f.action("cancelupgrade", existing, "canceled-upgrade")
+}
+
+func (f *fakeServices) ActionFinishupgrade(existing *client.Service) (*client.Service, error) {
+	return f.action("finishupgrade", existing, "active")
+}
+
+func (f *fakeServices) ActionRollback(existing *client.Service) (*client.Service, error) {
+	return f.action("rollback", existing, "active")
+}
+
+func (f *fakeServices) scale(id string) int64 {
+	f.Lock()
+	defer f.Unlock()
+	return f.services[id].Scale
+}
+
+func (f *fakeServices) recordedActions() []string {
+	f.Lock()
+	defer f.Unlock()
+	actions := append([]string{}, f.actions...)
+	sort.Strings(actions)
+	return actions
+}
+
+//fakeBaseClient reloads services from fakeServices, only upgrading services and services marked transitioning never settle
+type fakeBaseClient struct {
+	client.RancherBaseClient
+	services      *fakeServices
+	transitioning map[string]bool
+}
+
+func (f *fakeBaseClient) Reload(existing *client.Resource, output interface{}) error {
+	service, err := f.services.ById(existing.Id)
+	if err != nil || service == nil {
+		return fmt.Errorf("Service %s doesn't exist", existing.Id)
+	}
+	reloaded, ok := output.(*client.Service)
+	if !ok {
+		return fmt.Errorf("Unexpected reload of %#v", output)
+	}
+	*reloaded = *service
+	reloaded.Transitioning = "no"
+	if f.transitioning[existing.Id] || reloaded.State == "upgrading" {
+		reloaded.Transitioning = "yes"
+	}
+	return nil
+}
+
+//fakeGenericObjects keeps receivers and cooldowns in memory, updates replace their resourceData
+type fakeGenericObjects struct {
+	client.GenericObjectOperations
+	objects map[string]*client.GenericObject
+	created int
+}
+
+func (f *fakeGenericObjects) Create(object *client.GenericObject) (*client.GenericObject, error) {
+	f.created++
+	created := *object
+	created.Id = fmt.Sprintf("1go%d", f.created)
+	f.objects[created.Id] = &created
+	return &created, nil
+}
+
+func (f *fakeGenericObjects) List(opts *client.ListOpts) (*client.GenericObjectCollection, error) {
+	objects := []client.GenericObject{}
+	for _, object := range f.objects {
+		if kind, ok := opts.Filters["kind"]; ok && kind != object.Kind {
+			continue
+		}
+		if name, ok := opts.Filters["name"]; ok && name != object.Name {
+			continue
+		}
+		objects = append(objects, *object)
+	}
+	return &client.GenericObjectCollection{Data: objects}, nil
+}
+
+func (f *fakeGenericObjects) Delete(object *client.GenericObject) error {
+	delete(f.objects, object.Id)
+	return nil
+}
+
+func (f *fakeGenericObjects) ById(id string) (*client.GenericObject, error) {
+	object, ok := f.objects[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *object
+	return &copied, nil
+}
+
+func (f *fakeGenericObjects) Update(existing *client.GenericObject, updates interface{}) (*client.GenericObject, error) {
+	object, ok := f.objects[existing.Id]
+	if !ok {
+		return nil, fmt.Errorf("GenericObject %s doesn't exist", existing.Id)
+	}
+	update, ok := updates.(*client.GenericObject)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected update %#v", updates)
+	}
+	object.ResourceData = update.ResourceData
+	copied := *object
+	return &copied, nil
+}
+
+//receiver adds a receiver to the fake client and returns its id
+func receiver(apiClient *client.RancherClient, id string) string {
+	apiClient.GenericObject.(*fakeGenericObjects).objects[id] = &client.GenericObject{
+		Resource:     client.Resource{Id: id},
+		Kind:         "webhookReceiver",
+		ResourceData: map[string]interface{}{},
+	}
+	return id
+}
+
+//fakeHostEvents records the hosts scale host asked to evacuate and delete
+type fakeHostEvents struct {
+	client.ExternalHostEventOperations
+	deleted []string
+}
+
+func (f *fakeHostEvents) Create(event *client.ExternalHostEvent) (*client.ExternalHostEvent, error) {
+	f.deleted = append(f.deleted, event.HostId)
+	return event, nil
+}
+
+//configMap converts a driver config to the map form drivers receive once it is stored on a receiver
+func configMap(t *testing.T, config interface{}) map[string]interface{} {
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := map[string]interface{}{}
+	if err := json.Unmarshal(bytes, &conf); err != nil {
+		t.Fatal(err)
+	}
+	return conf
+}
+
+//jobRequest returns a request for the receiver carrying body and a job the driver reports to
+func jobRequest(t *testing.T, receiverID string, body string) (*http.Request, *Job) {
+	request, err := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := NewJob("1j1", "1a1", receiverID, "test")
+	return RequestWithJob(request, job), job
+}
+
+//result returns the state and message the job recorded for resourceID
+func result(job *Job, resourceID string) (string, string) {
+	for _, result := range job.Resource().Results {
+		if result.ResourceID == resourceID {
+			return result.State, result.Message
+		}
+	}
+	return "", ""
+}
@@ -24,7 +24,10 @@ type WebhookDriver interface {
 func RegisterDrivers() {
 	Drivers = map[string]WebhookDriver{}
 	Drivers["scaleService"] = &ScaleServiceDriver{}
+	Drivers["scaleStack"] = &ScaleStackDriver{}
 	Drivers["serviceUpgrade"] = &ServiceUpgradeDriver{}
+	Drivers["restartService"] = &RestartServiceDriver{}
+	Drivers["rollbackService"] = &RollbackServiceDriver{}
 	Drivers["scaleHost"] = &ScaleHostDriver{}
 	Drivers["forwardPost"] = &ForwardPostDriver{}
 }
@@ -0,0 +1,213 @@
+package drivers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	v1client "github.com/rancher/go-rancher/client"
+	"github.com/rancher/webhook-service/model"
+)
+
+const (
+	JobStateRunning   = "running"
+	JobStateSucceeded = "succeeded"
+	JobStateFailed    = "failed"
+
+	ResultStateError       = "error"
+	ResultStateScaled      = "scaled"
+	ResultStateUnchanged   = "unchanged"
+	ResultStateQueued      = "queued"
+	ResultStateUpgrading   = "upgrading"
+	ResultStateUpgraded    = "upgraded"
+	ResultStateSkipped     = "skipped"
+	ResultStateSuperseded  = "superseded"
+	ResultStateAborted     = "aborted"
+	ResultStateRestarting  = "restarting"
+	ResultStateRestarted   = "restarted"
+	ResultStateRollingBack = "rollingBack"
+	ResultStateRolledBack  = "rolledBack"
+)
+
+type jobContextKey struct{}
+
+//Job tracks a single driver execution, including work the driver leaves running in the background.
+//All methods are safe to call on a nil Job so drivers can be executed without one.
+type Job struct {
+	sync.Mutex
+	job       model.Job
+	projectID string
+	pending   int
+	executed  bool
+	done      chan struct{}
+}
+
+//NewJob creates a running job
+func NewJob(id string, projectID string, receiverID string, driver string) *Job {
+	return &Job{
+		job: model.Job{
+			Resource: v1client.Resource{
+				Id:   id,
+				Type: "job",
+			},
+			ReceiverID: receiverID,
+			Driver:     driver,
+			State:      JobStateRunning,
+			Created:    time.Now().UTC().Format(time.RFC3339),
+			Results:    []model.JobResult{},
+		},
+		projectID: projectID,
+		done:      make(chan struct{}),
+	}
+}
+
+//RequestWithJob returns a copy of request carrying job for the driver
+func RequestWithJob(request *http.Request, job *Job) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), jobContextKey{}, job))
+}
+
+//JobFromRequest returns the job attached to request, nil if there is none
+func JobFromRequest(request *http.Request) *Job {
+	job, _ := request.Context().Value(jobContextKey{}).(*Job)
+	return job
+}
+
+func (j *Job) ID() string {
+	if j == nil {
+		return ""
+	}
+	return j.job.Id
+}
+
+func (j *Job) ProjectID() string {
+	if j == nil {
+		return ""
+	}
+	return j.projectID
+}
+
+func (j *Job) ReceiverID() string {
+	if j == nil {
+		return ""
+	}
+	return j.job.ReceiverID
+}
+
+//SetResult records the outcome for a single resource touched by the driver
+func (j *Job) SetResult(resourceID string, state string, message string) {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+
+	for i, result := range j.job.Results {
+		if result.ResourceID == resourceID {
+			j.job.Results[i].State = state
+			j.job.Results[i].Message = message
+			return
+		}
+	}
+	j.job.Results = append(j.job.Results, model.JobResult{
+		ResourceID: resourceID,
+		State:      state,
+		Message:    message,
+	})
+}
+
+func (j *Job) SetMessage(message string) {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	j.job.Message = message
+}
+
+//Fail records an error of background work, failing the job once it finishes
+func (j *Job) Fail(err error) {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	j.job.Error = err.Error()
+}
+
+//Begin marks the start of background work that outlives Execute. Every Begin must be matched by an End
+func (j *Job) Begin() {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	j.pending++
+}
+
+func (j *Job) End() {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	j.pending--
+	j.finishIfDone()
+}
+
+//Complete records the outcome of Execute. The job finishes once all background work has ended
+func (j *Job) Complete(err error) {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	j.executed = true
+	if err != nil {
+		j.job.Error = err.Error()
+	}
+	j.finishIfDone()
+}
+
+func (j *Job) finishIfDone() {
+	if !j.executed || j.pending > 0 || j.job.State != JobStateRunning {
+		return
+	}
+
+	j.job.State = JobStateSucceeded
+	if j.job.Error != "" {
+		j.job.State = JobStateFailed
+	}
+	for _, result := range j.job.Results {
+		if result.State == ResultStateError {
+			j.job.State = JobStateFailed
+		}
+	}
+	j.job.Finished = time.Now().UTC().Format(time.RFC3339)
+	close(j.done)
+}
+
+func (j *Job) Running() bool {
+	if j == nil {
+		return false
+	}
+	j.Lock()
+	defer j.Unlock()
+	return j.job.State == JobStateRunning
+}
+
+//Done is closed once the job has finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+//Resource returns a copy of the job for use in API responses
+func (j *Job) Resource() model.Job {
+	j.Lock()
+	defer j.Unlock()
+
+	job := j.job
+	job.Results = make([]model.JobResult, len(j.job.Results))
+	copy(job.Results, j.job.Results)
+	return job
+}
@@ -0,0 +1,80 @@
+package drivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//LookupPath walks a decoded JSON payload along a dot separated path, numeric segments index into arrays
+func LookupPath(payload interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+	current := payload
+	for _, segment := range strings.Split(path, ".") {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, ok := value[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			current = value[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+//readRequestBody reads the whole request body, requests without a body give an empty payload
+func readRequestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil {
+		return nil, nil
+	}
+	bytes, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading request body: %v", err)
+	}
+	return bytes, nil
+}
+
+//lookupCount reads a non negative count from a JSON payload, fractions are rounded up
+func lookupCount(payload []byte, path string) (int64, error) {
+	var body interface{}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return 0, fmt.Errorf("Payload is not valid JSON: %v", err)
+	}
+	value, ok := LookupPath(body, path)
+	if !ok {
+		return 0, fmt.Errorf("Payload has no value at %s", path)
+	}
+
+	var number float64
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("Value %q at %s is not a number", v, path)
+		}
+		number = parsed
+	default:
+		return 0, fmt.Errorf("Value %v at %s is not a number", value, path)
+	}
+	if math.IsNaN(number) || math.IsInf(number, 0) || number < 0 || number > math.MaxInt32 {
+		return 0, fmt.Errorf("Value %v at %s is not a valid count", value, path)
+	}
+	return int64(math.Ceil(number)), nil
+}
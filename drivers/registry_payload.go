@@ -0,0 +1,263 @@
+package drivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	PayloadFormatDockerhub  = "dockerhub"
+	PayloadFormatAlicloud   = "alicloud"
+	PayloadFormatRegistryV2 = "registryV2"
+	PayloadFormatHarbor     = "harbor"
+	PayloadFormatQuay       = "quay"
+	PayloadFormatGithub     = "github"
+)
+
+//pushedImage is an image reported by a registry push notification
+type pushedImage struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+func (p pushedImage) String() string {
+	return p.Repository + ":" + p.Tag
+}
+
+//reference returns the image to deploy, pinned to the manifest digest when pinDigest is set and the registry reported one
+func (p pushedImage) reference(pinDigest bool) string {
+	if pinDigest && p.Digest != "" {
+		return p.Repository + "@" + p.Digest
+	}
+	return p.String()
+}
+
+//describe returns the deployed image along with the tag and digest it was resolved from
+func (p pushedImage) describe(pinDigest bool) string {
+	switch {
+	case p.Digest == "":
+		return p.String()
+	case pinDigest:
+		return fmt.Sprintf("%s (tag %s)", p.reference(pinDigest), p.Tag)
+	default:
+		return fmt.Sprintf("%s (digest %s)", p.String(), p.Digest)
+	}
+}
+
+//parsePushedImages extracts the images pushed according to a registry notification in the given payloadFormat
+func parsePushedImages(format string, payload []byte) ([]pushedImage, error) {
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("No Payload recevied from webhook")
+	}
+
+	var requestPayload interface{}
+	if err := json.Unmarshal(payload, &requestPayload); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling request body in Execute handler: %v", err)
+	}
+
+	requestBody, ok := requestPayload.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Body should be of type map[string]interface{}")
+	}
+
+	switch format {
+	case PayloadFormatRegistryV2:
+		return parseRegistryV2(requestBody)
+	case PayloadFormatGithub:
+		return parseGithub(requestBody)
+	case PayloadFormatHarbor:
+		return parseHarbor(requestBody)
+	case PayloadFormatQuay:
+		return parseQuay(requestBody)
+	case PayloadFormatAlicloud:
+		tag, repository, err := parsePushData(requestBody)
+		if err != nil {
+			return nil, err
+		}
+		fullName, fullNameOk := repository["repo_full_name"].(string)
+		region, regionOk := repository["region"].(string)
+		if !fullNameOk || !regionOk {
+			return nil, fmt.Errorf("Alicloud Docker Hub response provided without image name")
+		}
+		return []pushedImage{{Repository: "registry." + region + ".aliyuncs.com/" + fullName, Tag: tag}}, nil
+	default:
+		tag, repository, err := parsePushData(requestBody)
+		if err != nil {
+			return nil, err
+		}
+		imageName, ok := repository["repo_name"].(string)
+		if !ok {
+			return nil, fmt.Errorf("Response provided without image name")
+		}
+		return []pushedImage{{Repository: imageName, Tag: tag}}, nil
+	}
+}
+
+//parsePushData reads the push_data and repository sections shared by Docker Hub style payloads
+func parsePushData(requestBody map[string]interface{}) (string, map[string]interface{}, error) {
+	pushedData, ok := requestBody["push_data"].(map[string]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("Incomplete webhook response provided")
+	}
+
+	tag, ok := pushedData["tag"].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("Webhook response contains no tag")
+	}
+
+	repository, ok := requestBody["repository"].(map[string]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("Response provided without repository information")
+	}
+	return tag, repository, nil
+}
+
+//parseRegistryV2 reads a Docker Registry v2 notification envelope, only tagged manifest pushes are returned
+func parseRegistryV2(requestBody map[string]interface{}) ([]pushedImage, error) {
+	events, ok := requestBody["events"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Registry notification provided without events")
+	}
+
+	images := []pushedImage{}
+	for _, e := range events {
+		event, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Registry notification contains an invalid event")
+		}
+		if action, _ := event["action"].(string); action != "push" {
+			continue
+		}
+
+		target, ok := event["target"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Registry push event provided without target")
+		}
+		tag, _ := target["tag"].(string)
+		if tag == "" {
+			continue
+		}
+		repository, ok := target["repository"].(string)
+		if !ok || repository == "" {
+			return nil, fmt.Errorf("Registry push event provided without repository")
+		}
+		digest, _ := target["digest"].(string)
+
+		if request, ok := event["request"].(map[string]interface{}); ok {
+			if host, _ := request["host"].(string); host != "" {
+				repository = host + "/" + repository
+			}
+		}
+		images = append(images, pushedImage{Repository: repository, Tag: tag, Digest: digest})
+	}
+	return images, nil
+}
+
+//parseHarbor reads a Harbor push artifact event, other event types give no images
+func parseHarbor(requestBody map[string]interface{}) ([]pushedImage, error) {
+	eventType, _ := requestBody["type"].(string)
+	if eventType != "PUSH_ARTIFACT" && eventType != "pushImage" {
+		return []pushedImage{}, nil
+	}
+
+	eventData, ok := requestBody["event_data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Harbor event provided without event_data")
+	}
+	repository, _ := eventData["repository"].(map[string]interface{})
+	fullName, _ := repository["repo_full_name"].(string)
+	resources, ok := eventData["resources"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Harbor event provided without resources")
+	}
+
+	images := []pushedImage{}
+	for _, r := range resources {
+		resource, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Harbor event contains an invalid resource")
+		}
+		tag, _ := resource["tag"].(string)
+		if tag == "" {
+			continue
+		}
+		digest, _ := resource["digest"].(string)
+
+		//resource_url carries the registry host, fall back to the bare repository name without it
+		imageName := fullName
+		if resourceURL, _ := resource["resource_url"].(string); strings.HasSuffix(resourceURL, ":"+tag) {
+			imageName = strings.TrimSuffix(resourceURL, ":"+tag)
+		}
+		if imageName == "" {
+			return nil, fmt.Errorf("Harbor event provided without image name")
+		}
+		images = append(images, pushedImage{Repository: imageName, Tag: tag, Digest: digest})
+	}
+	return images, nil
+}
+
+//parseQuay reads a Quay repository push notification, every updated tag gives an image
+func parseQuay(requestBody map[string]interface{}) ([]pushedImage, error) {
+	dockerURL, ok := requestBody["docker_url"].(string)
+	if !ok || dockerURL == "" {
+		return nil, fmt.Errorf("Quay notification provided without docker_url")
+	}
+	updatedTags, ok := requestBody["updated_tags"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Quay notification provided without updated_tags")
+	}
+
+	images := []pushedImage{}
+	for _, t := range updatedTags {
+		tag, ok := t.(string)
+		if !ok || tag == "" {
+			return nil, fmt.Errorf("Quay notification contains an invalid tag")
+		}
+		images = append(images, pushedImage{Repository: dockerURL, Tag: tag})
+	}
+	return images, nil
+}
+
+//parseGithub reads a GitHub package or registry_package event for a container image,
+//only published and updated actions give an image so deletes are ignored
+func parseGithub(requestBody map[string]interface{}) ([]pushedImage, error) {
+	action, ok := requestBody["action"].(string)
+	if !ok {
+		return nil, fmt.Errorf("GitHub event provided without action")
+	}
+	if action != "published" && action != "updated" {
+		return []pushedImage{}, nil
+	}
+
+	pkg, ok := requestBody["registry_package"].(map[string]interface{})
+	if !ok {
+		if pkg, ok = requestBody["package"].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("GitHub event provided without package")
+		}
+	}
+	packageType, _ := pkg["package_type"].(string)
+	if !strings.EqualFold(packageType, "container") && !strings.EqualFold(packageType, "docker") {
+		return []pushedImage{}, nil
+	}
+
+	version, ok := pkg["package_version"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("GitHub event provided without package_version")
+	}
+	tag, _ := LookupPath(version, "container_metadata.tag.name")
+	tagName, _ := tag.(string)
+	if tagName == "" {
+		return []pushedImage{}, nil
+	}
+	digest, _ := LookupPath(version, "container_metadata.tag.digest")
+	digestName, _ := digest.(string)
+
+	packageURL, _ := version["package_url"].(string)
+	if !strings.HasSuffix(packageURL, ":"+tagName) {
+		return nil, fmt.Errorf("GitHub event provided without image name")
+	}
+	imageName := strings.TrimSuffix(packageURL, ":"+tagName)
+	return []pushedImage{{Repository: imageName, Tag: tagName, Digest: digestName}}, nil
+}
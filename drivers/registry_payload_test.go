@@ -0,0 +1,308 @@
+package drivers
+
+import (
+	"reflect"
+	"testing"
+)
+
+const dockerhubPayload = `{
+	"push_data": {"pushed_at": 1417566161, "pusher": "trustedbuilder", "tag": "1.2.0"},
+	"repository": {"repo_name": "rancher/webhook-service", "name": "webhook-service", "namespace": "rancher"}
+}`
+
+const alicloudPayload = `{
+	"push_data": {"digest": "sha256:457f4aa83fc9a6663ab9d1b0a6e2dce25a12a943ed5bf2c1747c58d48bbb4917", "pushed_at": "2016-11-29 12:25:46", "tag": "1.2.0"},
+	"repository": {"date_created": "2016-10-28 21:31:42", "name": "webhook-service", "namespace": "rancher", "region": "cn-hangzhou", "repo_full_name": "rancher/webhook-service", "repo_type": "PUBLIC"}
+}`
+
+const registryV2Payload = `{
+	"events": [
+		{
+			"id": "320678d8-ca14-430f-8bb6-4ca139cd83f7",
+			"timestamp": "2016-03-09T14:44:26.402973972-08:00",
+			"action": "push",
+			"target": {
+				"mediaType": "application/octet-stream",
+				"size": 708,
+				"digest": "sha256:fea8895f450959fa676bcc1df0611ea93823a735a01205fd8622846041d0c7cf",
+				"repository": "rancher/webhook-service"
+			},
+			"request": {"id": "6df24a34-0959-4923-81ca-14f09767db19", "host": "registry.example.com:5000", "method": "PUT"}
+		},
+		{
+			"id": "6b2e7ae3-1a1e-4a3e-a5d9-8e3d5cd30f55",
+			"timestamp": "2016-03-09T14:44:26.505481752-08:00",
+			"action": "push",
+			"target": {
+				"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+				"size": 1365,
+				"digest": "sha256:b6a5a2cc3b5d3a2b0a2b6e8d0fa7c3b0b7c1d0e2b1a3f2e4d5c6b7a8f9e0d1c2",
+				"repository": "rancher/webhook-service",
+				"tag": "1.2.0"
+			},
+			"request": {"id": "a5c3b3d4-3b2f-4e3a-9d7c-1a2b3c4d5e6f", "host": "registry.example.com:5000", "method": "PUT"}
+		},
+		{
+			"id": "9b1c7f0e-4c2a-4b6e-8d2a-0e1f2a3b4c5d",
+			"timestamp": "2016-03-09T14:45:01.117249712-08:00",
+			"action": "pull",
+			"target": {
+				"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+				"digest": "sha256:b6a5a2cc3b5d3a2b0a2b6e8d0fa7c3b0b7c1d0e2b1a3f2e4d5c6b7a8f9e0d1c2",
+				"repository": "rancher/webhook-service",
+				"tag": "1.1.0"
+			},
+			"request": {"id": "1f2e3d4c-5b6a-4978-8695-a4b3c2d1e0f9", "host": "registry.example.com:5000", "method": "GET"}
+		}
+	]
+}`
+
+const harborPayload = `{
+	"type": "PUSH_ARTIFACT",
+	"occur_at": 1600831430,
+	"operator": "admin",
+	"event_data": {
+		"resources": [
+			{
+				"digest": "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8",
+				"tag": "1.2.0",
+				"resource_url": "harbor.example.com/rancher/webhook-service:1.2.0"
+			}
+		],
+		"repository": {"date_created": 1600831430, "name": "webhook-service", "namespace": "rancher", "repo_full_name": "rancher/webhook-service", "repo_type": "private"}
+	}
+}`
+
+const harborDeletePayload = `{
+	"type": "DELETE_ARTIFACT",
+	"occur_at": 1600831430,
+	"operator": "admin",
+	"event_data": {
+		"resources": [{"digest": "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8", "tag": "1.2.0", "resource_url": "harbor.example.com/rancher/webhook-service:1.2.0"}],
+		"repository": {"name": "webhook-service", "namespace": "rancher", "repo_full_name": "rancher/webhook-service"}
+	}
+}`
+
+const quayPayload = `{
+	"repository": "rancher/webhook-service",
+	"namespace": "rancher",
+	"name": "webhook-service",
+	"docker_url": "quay.io/rancher/webhook-service",
+	"homepage": "https://quay.io/repository/rancher/webhook-service",
+	"updated_tags": ["1.2.0", "latest"]
+}`
+
+//gitlabRegistryPayload is sent by the GitLab container registry, a distribution registry using registryV2 notifications
+const gitlabRegistryPayload = `{
+	"events": [
+		{
+			"id": "0f4ba2b5-7e43-4c4a-8a24-3a0b2f0d6a51",
+			"timestamp": "2018-01-15T10:12:01.883Z",
+			"action": "delete",
+			"target": {"digest": "sha256:5d4a1b0c9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b", "repository": "rancher/webhook-service"},
+			"request": {"id": "c0b9a8f7-e6d5-4c4b-a3a2-918070605040", "host": "registry.gitlab.com", "method": "DELETE"}
+		},
+		{
+			"id": "2d7b8f1a-4e5c-4f6d-9a0b-1c2d3e4f5a6b",
+			"timestamp": "2018-01-15T10:14:22.107Z",
+			"action": "push",
+			"target": {
+				"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+				"digest": "sha256:0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b",
+				"repository": "rancher/webhook-service",
+				"tag": "1.2.0"
+			},
+			"request": {"id": "7e6d5c4b-3a29-4180-9f8e-7d6c5b4a3928", "host": "registry.gitlab.com", "method": "PUT"}
+		}
+	]
+}`
+
+const githubPayload = `{
+	"action": "published",
+	"registry_package": {
+		"id": 1234567,
+		"name": "webhook-service",
+		"namespace": "rancher",
+		"ecosystem": "CONTAINER",
+		"package_type": "CONTAINER",
+		"package_version": {
+			"id": 7654321,
+			"version": "sha256:9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b",
+			"container_metadata": {
+				"tag": {"name": "1.2.0", "digest": "sha256:9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b"}
+			},
+			"package_url": "ghcr.io/rancher/webhook-service:1.2.0"
+		},
+		"registry": {"about_url": "https://docs.github.com/packages/learn-github-packages/introduction-to-github-packages", "name": "GitHub CONTAINER registry", "type": "CONTAINER", "url": "https://ghcr.io/rancher"}
+	},
+	"repository": {"full_name": "rancher/webhook-service"}
+}`
+
+const githubDeletePayload = `{
+	"action": "deleted",
+	"registry_package": {
+		"name": "webhook-service",
+		"package_type": "CONTAINER",
+		"package_version": {
+			"container_metadata": {"tag": {"name": "1.2.0", "digest": "sha256:9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b"}},
+			"package_url": "ghcr.io/rancher/webhook-service:1.2.0"
+		}
+	}
+}`
+
+func TestParsePushedImages(t *testing.T) {
+	tests := []struct {
+		format   string
+		payload  string
+		expected []pushedImage
+	}{
+		{PayloadFormatDockerhub, dockerhubPayload, []pushedImage{
+			{Repository: "rancher/webhook-service", Tag: "1.2.0"},
+		}},
+		{"", dockerhubPayload, []pushedImage{
+			{Repository: "rancher/webhook-service", Tag: "1.2.0"},
+		}},
+		{PayloadFormatAlicloud, alicloudPayload, []pushedImage{
+			{Repository: "registry.cn-hangzhou.aliyuncs.com/rancher/webhook-service", Tag: "1.2.0"},
+		}},
+		{PayloadFormatRegistryV2, registryV2Payload, []pushedImage{
+			{Repository: "registry.example.com:5000/rancher/webhook-service", Tag: "1.2.0",
+				Digest: "sha256:b6a5a2cc3b5d3a2b0a2b6e8d0fa7c3b0b7c1d0e2b1a3f2e4d5c6b7a8f9e0d1c2"},
+		}},
+		{PayloadFormatHarbor, harborPayload, []pushedImage{
+			{Repository: "harbor.example.com/rancher/webhook-service", Tag: "1.2.0",
+				Digest: "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8"},
+		}},
+		{PayloadFormatHarbor, harborDeletePayload, []pushedImage{}},
+		{PayloadFormatQuay, quayPayload, []pushedImage{
+			{Repository: "quay.io/rancher/webhook-service", Tag: "1.2.0"},
+			{Repository: "quay.io/rancher/webhook-service", Tag: "latest"},
+		}},
+		{PayloadFormatRegistryV2, gitlabRegistryPayload, []pushedImage{
+			{Repository: "registry.gitlab.com/rancher/webhook-service", Tag: "1.2.0",
+				Digest: "sha256:0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b"},
+		}},
+		{PayloadFormatGithub, githubPayload, []pushedImage{
+			{Repository: "ghcr.io/rancher/webhook-service", Tag: "1.2.0",
+				Digest: "sha256:9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b"},
+		}},
+		{PayloadFormatGithub, githubDeletePayload, []pushedImage{}},
+	}
+
+	for _, test := range tests {
+		images, err := parsePushedImages(test.format, []byte(test.payload))
+		if err != nil {
+			t.Fatalf("Format %q: unexpected error %v", test.format, err)
+		}
+		if !reflect.DeepEqual(images, test.expected) {
+			t.Fatalf("Format %q: expected %v, got %v", test.format, test.expected, images)
+		}
+	}
+}
+
+func TestParsePushedImagesInvalid(t *testing.T) {
+	tests := []struct {
+		format  string
+		payload string
+	}{
+		{PayloadFormatDockerhub, ``},
+		{PayloadFormatDockerhub, `not json`},
+		{PayloadFormatDockerhub, `{"repository": {"repo_name": "rancher/webhook-service"}}`},
+		{PayloadFormatAlicloud, dockerhubPayload},
+		{PayloadFormatRegistryV2, dockerhubPayload},
+		{PayloadFormatRegistryV2, `{"events": [{"action": "push", "target": {"tag": "1.2.0"}}]}`},
+		{PayloadFormatHarbor, `{"type": "PUSH_ARTIFACT"}`},
+		{PayloadFormatQuay, `{"docker_url": "quay.io/rancher/webhook-service"}`},
+		{PayloadFormatQuay, dockerhubPayload},
+		{PayloadFormatRegistryV2, githubPayload},
+		{PayloadFormatGithub, `{"registry_package": {"package_type": "CONTAINER"}}`},
+		{PayloadFormatGithub, `{"action": "published", "registry_package": {"package_type": "CONTAINER"}}`},
+	}
+
+	for _, test := range tests {
+		if images, err := parsePushedImages(test.format, []byte(test.payload)); err == nil {
+			t.Fatalf("Format %q: expected error for payload %s, got %v", test.format, test.payload, images)
+		}
+	}
+}
+
+func TestPushedImageReference(t *testing.T) {
+	digest := "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8"
+	tests := []struct {
+		image       pushedImage
+		pinDigest   bool
+		reference   string
+		description string
+	}{
+		{pushedImage{Repository: "rancher/webhook-service", Tag: "1.2.0"}, false,
+			"rancher/webhook-service:1.2.0", "rancher/webhook-service:1.2.0"},
+		{pushedImage{Repository: "rancher/webhook-service", Tag: "1.2.0"}, true,
+			"rancher/webhook-service:1.2.0", "rancher/webhook-service:1.2.0"},
+		{pushedImage{Repository: "harbor.example.com/rancher/webhook-service", Tag: "1.2.0", Digest: digest}, false,
+			"harbor.example.com/rancher/webhook-service:1.2.0", "harbor.example.com/rancher/webhook-service:1.2.0 (digest " + digest + ")"},
+		{pushedImage{Repository: "harbor.example.com/rancher/webhook-service", Tag: "1.2.0", Digest: digest}, true,
+			"harbor.example.com/rancher/webhook-service@" + digest, "harbor.example.com/rancher/webhook-service@" + digest + " (tag 1.2.0)"},
+	}
+
+	for _, test := range tests {
+		if reference := test.image.reference(test.pinDigest); reference != test.reference {
+			t.Fatalf("Expected reference %s, got %s", test.reference, reference)
+		}
+		if description := test.image.describe(test.pinDigest); description != test.description {
+			t.Fatalf("Expected description %s, got %s", test.description, description)
+		}
+	}
+}
+
+func TestParseRegistryV2(t *testing.T) {
+	manifest := func(action string, repository string, tag string, host string) string {
+		request := ""
+		if host != "" {
+			request = `, "request": {"host": "` + host + `", "method": "PUT"}`
+		}
+		return `{"action": "` + action + `", "target": {"mediaType": "application/vnd.docker.distribution.manifest.v2+json",` +
+			` "digest": "sha256:1111", "repository": "` + repository + `", "tag": "` + tag + `"}` + request + `}`
+	}
+	blob := `{"action": "push", "target": {"mediaType": "application/octet-stream", "digest": "sha256:2222", "repository": "rancher/webhook-service"}}`
+
+	tests := []struct {
+		events   string
+		expected []pushedImage
+	}{
+		{manifest("push", "rancher/webhook-service", "1.2.0", "registry.example.com:5000") + "," +
+			manifest("push", "rancher/webhook-service", "latest", "registry.example.com:5000"), []pushedImage{
+			{Repository: "registry.example.com:5000/rancher/webhook-service", Tag: "1.2.0", Digest: "sha256:1111"},
+			{Repository: "registry.example.com:5000/rancher/webhook-service", Tag: "latest", Digest: "sha256:1111"},
+		}},
+		{manifest("pull", "rancher/webhook-service", "1.2.0", "registry.example.com:5000"), []pushedImage{}},
+		{manifest("delete", "rancher/webhook-service", "1.2.0", ""), []pushedImage{}},
+		{blob, []pushedImage{}},
+		{blob + "," + manifest("push", "rancher/webhook-service", "1.2.0", ""), []pushedImage{
+			{Repository: "rancher/webhook-service", Tag: "1.2.0", Digest: "sha256:1111"},
+		}},
+		{"", []pushedImage{}},
+	}
+
+	for _, test := range tests {
+		payload := `{"events": [` + test.events + `]}`
+		images, err := parsePushedImages(PayloadFormatRegistryV2, []byte(payload))
+		if err != nil {
+			t.Fatalf("Unexpected error %v for %s", err, payload)
+		}
+		if !reflect.DeepEqual(images, test.expected) {
+			t.Fatalf("Expected %v, got %v for %s", test.expected, images, payload)
+		}
+	}
+
+	invalid := []string{
+		`{"events": "push"}`,
+		`{"events": ["push"]}`,
+		`{"events": [{"action": "push"}]}`,
+		`{"events": [{"action": "push", "target": {"tag": "1.2.0", "repository": ""}}]}`,
+	}
+	for _, payload := range invalid {
+		if images, err := parsePushedImages(PayloadFormatRegistryV2, []byte(payload)); err == nil {
+			t.Fatalf("Expected error for %s, got %v", payload, images)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package drivers
+
+import (
+	"fmt"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	v1client "github.com/rancher/go-rancher/client"
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+type RestartServiceDriver struct {
+}
+
+func (s *RestartServiceDriver) ValidatePayload(conf interface{}, apiClient *client.RancherClient) (int, error) {
+	config, ok := conf.(model.RestartService)
+	if !ok {
+		return http.StatusInternalServerError, fmt.Errorf("Can't process config")
+	}
+
+	if config.ServiceID == "" && len(config.ServiceSelector) == 0 {
+		return http.StatusBadRequest, fmt.Errorf("ServiceId or serviceSelector not provided")
+	}
+
+	if config.ServiceID != "" && len(config.ServiceSelector) > 0 {
+		return http.StatusBadRequest, fmt.Errorf("Only one of serviceId and serviceSelector can be provided")
+	}
+
+	if config.BatchSize <= 0 {
+		return http.StatusBadRequest, fmt.Errorf("Batch size for restart not provided/invalid")
+	}
+
+	if config.IntervalSeconds <= 0 {
+		return http.StatusBadRequest, fmt.Errorf("Batch interval for restart not provided/invalid")
+	}
+
+	if config.RestartTimeoutSeconds < 0 {
+		return http.StatusBadRequest, fmt.Errorf("Invalid restartTimeoutSeconds: %v", config.RestartTimeoutSeconds)
+	}
+
+	if config.ServiceID != "" {
+		service, err := apiClient.Service.ById(config.ServiceID)
+		if err != nil {
+			return http.StatusInternalServerError, errors.Wrap(err, "Error in getService")
+		}
+
+		if service == nil || service.Removed != "" {
+			return http.StatusBadRequest, fmt.Errorf("Invalid service %v", config.ServiceID)
+		}
+
+		if service.State != "active" {
+			return http.StatusBadRequest, fmt.Errorf("Service %v is %v, only active services can be restarted", config.ServiceID, service.State)
+		}
+		return http.StatusOK, nil
+	}
+
+	if err := validateSelector("serviceSelector", config.ServiceSelector); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	services, err := selectServices(apiClient, config.ServiceSelector)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	for _, service := range services {
+		if service.State == "active" {
+			return http.StatusOK, nil
+		}
+	}
+	return http.StatusBadRequest, fmt.Errorf("No active services match serviceSelector %v", config.ServiceSelector)
+}
+
+func (s *RestartServiceDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
+	config := &model.RestartService{}
+	err := mapstructure.Decode(conf, config)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "Couldn't unmarshal config")
+	}
+	job := JobFromRequest(request)
+
+	var services []client.Service
+	if config.ServiceID != "" {
+		service, err := apiClient.Service.ById(config.ServiceID)
+		if err != nil {
+			return http.StatusInternalServerError, errors.Wrap(err, "Error in getService")
+		}
+
+		if service == nil || service.Removed != "" {
+			return http.StatusBadRequest, fmt.Errorf("Service %v has been deleted", config.ServiceID)
+		}
+		services = []client.Service{*service}
+	} else {
+		services, err = selectServices(apiClient, config.ServiceSelector)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if len(services) == 0 {
+			job.SetMessage(fmt.Sprintf("No services matched serviceSelector %v", config.ServiceSelector))
+			return http.StatusOK, nil
+		}
+	}
+
+	for _, service := range services {
+		if service.State != "active" {
+			job.SetResult(service.Id, ResultStateError, fmt.Sprintf("Service is %s, only active services can be restarted", service.State))
+			continue
+		}
+
+		job.SetResult(service.Id, ResultStateRestarting, "Restarting service")
+		job.Begin()
+		go restartService(apiClient, service, config, job)
+	}
+
+	return http.StatusOK, nil
+}
+
+func restartService(apiClient *client.RancherClient, service client.Service, config *model.RestartService, job *Job) {
+	defer job.End()
+	log.Infof("Restarting service %s", service.Id)
+	restartedService, err := apiClient.Service.ActionRestart(&service, &client.ServiceRestart{
+		RollingRestartStrategy: client.RollingRestartStrategy{
+			BatchSize:      config.BatchSize,
+			IntervalMillis: config.IntervalSeconds * 1000,
+		},
+	})
+	if err != nil {
+		log.Errorf("Error %v in restarting service %s", err, service.Id)
+		job.SetResult(service.Id, ResultStateError, fmt.Sprintf("Error %v in restarting service", err))
+		return
+	}
+
+	if err := waitFor(apiClient, restartedService, config.RestartTimeoutSeconds, DefaultPollIntervalSeconds); err != nil {
+		log.Errorln(err)
+		job.SetResult(service.Id, ResultStateError, err.Error())
+		return
+	}
+	job.SetResult(service.Id, ResultStateRestarted, "Restarted service")
+}
+
+func (s *RestartServiceDriver) ConvertToConfigAndSetOnWebhook(conf interface{}, webhook *model.Webhook) error {
+	if restartConfig, ok := conf.(model.RestartService); ok {
+		webhook.RestartServiceConfig = restartConfig
+		webhook.RestartServiceConfig.Type = webhook.Driver
+		return nil
+	} else if configMap, ok := conf.(map[string]interface{}); ok {
+		config := model.RestartService{}
+		err := mapstructure.Decode(configMap, &config)
+		if err != nil {
+			return err
+		}
+		webhook.RestartServiceConfig = config
+		webhook.RestartServiceConfig.Type = webhook.Driver
+		return nil
+	}
+	return fmt.Errorf("Can't convert config %v", conf)
+}
+
+func (s *RestartServiceDriver) GetDriverConfigResource() interface{} {
+	return model.RestartService{}
+}
+
+func (s *RestartServiceDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Schema {
+	minValue := int64(1)
+
+	describeSelector(schema)
+
+	batchSize := schema.ResourceFields["batchSize"]
+	batchSize.Default = 1
+	batchSize.Min = &minValue
+	schema.ResourceFields["batchSize"] = batchSize
+
+	intervalSeconds := schema.ResourceFields["intervalSeconds"]
+	intervalSeconds.Default = 2
+	intervalSeconds.Min = &minValue
+	intervalSeconds.Description = "Seconds between restarting batches"
+	schema.ResourceFields["intervalSeconds"] = intervalSeconds
+
+	restartTimeoutSeconds := schema.ResourceFields["restartTimeoutSeconds"]
+	restartTimeoutSeconds.Default = DefaultUpgradeTimeoutSeconds
+	restartTimeoutSeconds.Min = &minValue
+	schema.ResourceFields["restartTimeoutSeconds"] = restartTimeoutSeconds
+
+	return schema
+}
@@ -0,0 +1,83 @@
+package drivers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestRestartServiceActiveState(t *testing.T) {
+	labeled := func(id string, state string, labels map[string]interface{}) client.Service {
+		return client.Service{
+			Resource:     client.Resource{Id: id},
+			State:        state,
+			LaunchConfig: &client.LaunchConfig{Labels: labels},
+		}
+	}
+	apiClient, services := newFakeClient(
+		labeled("1s1", "active", map[string]interface{}{"tier": "web"}),
+		labeled("1s2", "upgraded", map[string]interface{}{"tier": "web"}),
+		labeled("1s3", "inactive", map[string]interface{}{"tier": "web"}),
+		labeled("1s4", "active", map[string]interface{}{"tier": "db"}),
+		labeled("1s5", "inactive", map[string]interface{}{"tier": "cache"}),
+	)
+	driver := &RestartServiceDriver{}
+
+	tests := []struct {
+		config model.RestartService
+		code   int
+	}{
+		{model.RestartService{ServiceID: "1s1", BatchSize: 1, IntervalSeconds: 2}, http.StatusOK},
+		{model.RestartService{ServiceID: "1s3", BatchSize: 1, IntervalSeconds: 2}, http.StatusBadRequest},
+		{model.RestartService{ServiceID: "1s9", BatchSize: 1, IntervalSeconds: 2}, http.StatusBadRequest},
+		{model.RestartService{ServiceSelector: map[string]string{"tier": "web"}, BatchSize: 1, IntervalSeconds: 2}, http.StatusOK},
+		{model.RestartService{ServiceSelector: map[string]string{"tier": "cache"}, BatchSize: 1, IntervalSeconds: 2}, http.StatusBadRequest},
+		{model.RestartService{ServiceID: "1s1", BatchSize: 1, IntervalSeconds: 2, RestartTimeoutSeconds: -1}, http.StatusBadRequest},
+	}
+	for i, test := range tests {
+		if code, err := driver.ValidatePayload(test.config, apiClient); code != test.code {
+			t.Fatalf("Case %d: expected %d, got %d %v", i, test.code, code, err)
+		}
+	}
+
+	config := configMap(t, model.RestartService{
+		ServiceSelector:       map[string]string{"tier": "web"},
+		BatchSize:             2,
+		IntervalSeconds:       3,
+		RestartTimeoutSeconds: 60,
+	})
+	request, job := jobRequest(t, "1wr-restart", "")
+	code, err := driver.Execute(config, apiClient, request)
+	job.Complete(err)
+	if code != http.StatusOK || err != nil {
+		t.Fatalf("Unexpected %d %v", code, err)
+	}
+	select {
+	case <-job.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for restart")
+	}
+
+	if actions := services.recordedActions(); !reflect.DeepEqual(actions, []string{"restart 1s1"}) {
+		t.Fatalf("Only active services should restart, got %v", actions)
+	}
+	//intervalSeconds is passed on to the rolling restart in milliseconds
+	if strategy := services.restarts["1s1"]; strategy.BatchSize != 2 || strategy.IntervalMillis != 3000 {
+		t.Fatalf("Unexpected restart strategy %#v", strategy)
+	}
+	expected := map[string]string{
+		"1s1": ResultStateRestarted,
+		"1s2": ResultStateError,
+		"1s3": ResultStateError,
+		"1s4": "",
+	}
+	for id, state := range expected {
+		if actual, message := result(job, id); actual != state {
+			t.Fatalf("Service %s: expected result %q, got %q %q", id, state, actual, message)
+		}
+	}
+}
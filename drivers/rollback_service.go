@@ -0,0 +1,130 @@
+package drivers
+
+import (
+	"fmt"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	v1client "github.com/rancher/go-rancher/client"
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+type RollbackServiceDriver struct {
+}
+
+func (s *RollbackServiceDriver) ValidatePayload(conf interface{}, apiClient *client.RancherClient) (int, error) {
+	config, ok := conf.(model.RollbackService)
+	if !ok {
+		return http.StatusInternalServerError, fmt.Errorf("Can't process config")
+	}
+
+	if config.ServiceID == "" && len(config.ServiceSelector) == 0 {
+		return http.StatusBadRequest, fmt.Errorf("ServiceId or serviceSelector not provided")
+	}
+
+	if config.ServiceID != "" && len(config.ServiceSelector) > 0 {
+		return http.StatusBadRequest, fmt.Errorf("Only one of serviceId and serviceSelector can be provided")
+	}
+
+	if config.ServiceID == "" {
+		if err := validateSelector("serviceSelector", config.ServiceSelector); err != nil {
+			return http.StatusBadRequest, err
+		}
+		return http.StatusOK, nil
+	}
+
+	service, err := apiClient.Service.ById(config.ServiceID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "Error in getService")
+	}
+
+	if service == nil || service.Removed != "" {
+		return http.StatusBadRequest, fmt.Errorf("Invalid service %v", config.ServiceID)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (s *RollbackServiceDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
+	config := &model.RollbackService{}
+	err := mapstructure.Decode(conf, config)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "Couldn't unmarshal config")
+	}
+	job := JobFromRequest(request)
+
+	var services []client.Service
+	if config.ServiceID != "" {
+		service, err := apiClient.Service.ById(config.ServiceID)
+		if err != nil {
+			return http.StatusInternalServerError, errors.Wrap(err, "Error in getService")
+		}
+
+		if service == nil || service.Removed != "" {
+			return http.StatusBadRequest, fmt.Errorf("Service %v has been deleted", config.ServiceID)
+		}
+		services = []client.Service{*service}
+	} else {
+		services, err = selectServices(apiClient, config.ServiceSelector)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if len(services) == 0 {
+			job.SetMessage(fmt.Sprintf("No services matched serviceSelector %v", config.ServiceSelector))
+			return http.StatusOK, nil
+		}
+	}
+
+	for _, service := range services {
+		//only a service with a pending upgrade keeps the launch config to roll back to, a running upgrade is canceled first
+		if service.State != "upgraded" && service.State != "upgrading" && service.State != "canceled-upgrade" {
+			job.SetResult(service.Id, ResultStateError, fmt.Sprintf("Service is %s, only upgraded services can be rolled back", service.State))
+			continue
+		}
+
+		job.SetResult(service.Id, ResultStateRollingBack, "Rolling back service")
+		job.Begin()
+		go func(service client.Service) {
+			defer job.End()
+			log.Infof("Rolling back service %s", service.Id)
+			if err := rollback(apiClient, &service, DefaultUpgradeTimeoutSeconds, DefaultPollIntervalSeconds); err != nil {
+				log.Errorln(err)
+				job.SetResult(service.Id, ResultStateError, err.Error())
+				return
+			}
+			job.SetResult(service.Id, ResultStateRolledBack, "Rolled back service")
+		}(service)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (s *RollbackServiceDriver) ConvertToConfigAndSetOnWebhook(conf interface{}, webhook *model.Webhook) error {
+	if rollbackConfig, ok := conf.(model.RollbackService); ok {
+		webhook.RollbackServiceConfig = rollbackConfig
+		webhook.RollbackServiceConfig.Type = webhook.Driver
+		return nil
+	} else if configMap, ok := conf.(map[string]interface{}); ok {
+		config := model.RollbackService{}
+		err := mapstructure.Decode(configMap, &config)
+		if err != nil {
+			return err
+		}
+		webhook.RollbackServiceConfig = config
+		webhook.RollbackServiceConfig.Type = webhook.Driver
+		return nil
+	}
+	return fmt.Errorf("Can't convert config %v", conf)
+}
+
+func (s *RollbackServiceDriver) GetDriverConfigResource() interface{} {
+	return model.RollbackService{}
+}
+
+func (s *RollbackServiceDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Schema {
+	describeSelector(schema)
+	return schema
+}
@@ -0,0 +1,86 @@
+package drivers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestRollbackServiceUpgradedState(t *testing.T) {
+	labeled := func(id string, state string) client.Service {
+		return client.Service{
+			Resource:     client.Resource{Id: id},
+			State:        state,
+			LaunchConfig: &client.LaunchConfig{Labels: map[string]interface{}{"tier": "web"}},
+		}
+	}
+	apiClient, services := newFakeClient(
+		labeled("1s1", "upgraded"),
+		labeled("1s2", "upgrading"),
+		labeled("1s3", "active"),
+		labeled("1s4", "inactive"),
+		labeled("1s5", "upgraded"),
+		labeled("1s6", "canceled-upgrade"),
+	)
+	services.failing["1s5"] = true
+
+	config := configMap(t, model.RollbackService{ServiceSelector: map[string]string{"tier": "web"}})
+	request, job := jobRequest(t, "1wr-rollback", "")
+	code, err := (&RollbackServiceDriver{}).Execute(config, apiClient, request)
+	job.Complete(err)
+	if code != http.StatusOK || err != nil {
+		t.Fatalf("Unexpected %d %v", code, err)
+	}
+	select {
+	case <-job.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for rollback")
+	}
+
+	//an upgrading service is canceled before it can roll back
+	if actions := services.recordedActions(); !reflect.DeepEqual(actions,
+		[]string{"cancelupgrade 1s2", "rollback 1s1", "rollback 1s2", "rollback 1s5", "rollback 1s6"}) {
+		t.Fatalf("Only services with a pending upgrade should roll back, got %v", actions)
+	}
+	expected := map[string]string{
+		"1s1": ResultStateRolledBack,
+		"1s2": ResultStateRolledBack,
+		"1s3": ResultStateError,
+		"1s4": ResultStateError,
+		"1s5": ResultStateError,
+		"1s6": ResultStateRolledBack,
+	}
+	for id, state := range expected {
+		if actual, message := result(job, id); actual != state {
+			t.Fatalf("Service %s: expected result %q, got %q %q", id, state, actual, message)
+		}
+	}
+	if state := job.Resource().State; state != JobStateFailed {
+		t.Fatalf("Expected failed job, got %s", state)
+	}
+
+	config = configMap(t, model.RollbackService{ServiceID: "1s9"})
+	request, _ = jobRequest(t, "1wr-rollback", "")
+	if code, err := (&RollbackServiceDriver{}).Execute(config, apiClient, request); code != http.StatusBadRequest || err == nil {
+		t.Fatalf("Expected bad request for a deleted service, got %d %v", code, err)
+	}
+}
+
+func TestRollbackWaitsForService(t *testing.T) {
+	apiClient, services := newFakeClient(client.Service{Resource: client.Resource{Id: "1s1"}, State: "upgraded"})
+	if err := rollback(apiClient, &client.Service{Resource: client.Resource{Id: "1s1"}}, 60, 1); err != nil {
+		t.Fatalf("Unexpected %v", err)
+	}
+	if service, _ := services.ById("1s1"); service.State != "active" {
+		t.Fatalf("Expected rolled back service to be active, got %s", service.State)
+	}
+
+	services.failing["1s1"] = true
+	if err := rollback(apiClient, &client.Service{Resource: client.Resource{Id: "1s1"}}, 60, 1); err == nil {
+		t.Fatal("Expected failed rollback to return an error")
+	}
+}
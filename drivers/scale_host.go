@@ -77,6 +77,14 @@ func (s *ScaleHostDriver) ValidatePayload(conf interface{}, apiClient *client.Ra
 		}
 	}
 
+	if code, err := validateBoundsBehavior(config.BoundsBehavior); err != nil {
+		return code, err
+	}
+
+	if config.CooldownSeconds < 0 {
+		return http.StatusBadRequest, fmt.Errorf("Invalid cooldownSeconds: %v", config.CooldownSeconds)
+	}
+
 	if config.Action == "down" {
 		if config.DeleteOption != "mostRecent" && config.DeleteOption != "leastRecent" {
 			return http.StatusBadRequest, fmt.Errorf("Invalid delete option/Delete option missing %v", config.DeleteOption)
@@ -87,15 +95,30 @@ func (s *ScaleHostDriver) ValidatePayload(conf interface{}, apiClient *client.Ra
 }
 
 func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
-	var currNameSuffix, baseHostName, currCloneName, suffix, key, value string
-	var count, newHostScale, baseHostIndex int64
-
 	config := &model.ScaleHost{}
 	err := mapstructure.Decode(conf, config)
 	if err != nil {
 		return http.StatusInternalServerError, errors.Wrap(err, "Couldn't unmarshal config")
 	}
 
+	job := JobFromRequest(request)
+	if remaining := cooldowns.reserve(apiClient, job.ReceiverID(), config.CooldownSeconds); remaining > 0 {
+		log.Infof("Receiver %s is cooling down for another %v, skipping scale of hosts", job.ReceiverID(), remaining)
+		job.SetMessage(skippedCoolingDown)
+		return http.StatusOK, nil
+	}
+
+	changed, code, err := scaleHosts(config, apiClient, job)
+	cooldowns.release(apiClient, job.ReceiverID(), changed)
+	return code, err
+}
+
+//scaleHosts returns whether any host was created or removed, also when it fails part way
+func scaleHosts(config *model.ScaleHost, apiClient *client.RancherClient, job *Job) (bool, int, error) {
+	var currNameSuffix, baseHostName, currCloneName, suffix, key, value string
+	var count, newHostScale, baseHostIndex int64
+	var change *scaleChange
+
 	action := config.Action
 	amount := config.Amount
 	max := config.Max
@@ -104,11 +127,11 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 		hostTemplate, err := apiClient.HostTemplate.ById(config.HostTemplateID)
 		if err != nil {
 			log.Errorf("Cannot get hostTemplate resource: %v", err)
-			return http.StatusBadRequest, fmt.Errorf("Cannot get hostTemplate resource")
+			return false, http.StatusBadRequest, fmt.Errorf("Cannot get hostTemplate resource")
 		}
 
 		if hostTemplate == nil || hostTemplate.Removed != "" {
-			return http.StatusBadRequest, fmt.Errorf("hostTemplate does not exist")
+			return false, http.StatusBadRequest, fmt.Errorf("hostTemplate does not exist")
 		}
 
 		filters := make(map[string]interface{})
@@ -147,10 +170,13 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 			baseSuffix := re.FindString(baseHostName)
 			basePrefix := strings.TrimRight(baseHostName, baseSuffix)
 
-			newHostScale = amount + int64(len(hostScalingGroup))
-			if newHostScale > max {
-				return http.StatusBadRequest, fmt.Errorf("Cannot scale above provided max scale value")
+			current := int64(len(hostScalingGroup))
+			newHostScale, err = boundScale(current, current+amount, current, max, config.BoundsBehavior)
+			if err != nil {
+				return false, http.StatusBadRequest, err
 			}
+			change = &scaleChange{from: current, requested: current + amount, to: newHostScale}
+			amount = newHostScale - current
 
 			// Get the most recently created host with same prefix as base host, this will have largest suffix
 			suffix = ""
@@ -175,7 +201,7 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 				if suffix != "" {
 					prevNumber, err := strconv.Atoi(suffix)
 					if err != nil {
-						return http.StatusInternalServerError, fmt.Errorf("Error converting %s to int in scaleHost driver: %v", suffix, err)
+						return count > 0, http.StatusInternalServerError, fmt.Errorf("Error converting %s to int in scaleHost driver: %v", suffix, err)
 					}
 					currNumber := prevNumber + 1
 					currNameSuffix = leftPad(strconv.Itoa(currNumber), "0", len(suffix))
@@ -195,14 +221,14 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 				_, err := apiClient.Host.Create(&hst)
 				if err != nil {
 					log.Errorf("Cannot create host: %v", err)
-					return http.StatusInternalServerError, fmt.Errorf("Cannot create host")
+					return count > 0, http.StatusInternalServerError, fmt.Errorf("Cannot create host")
 				}
 
 				suffix = currNameSuffix
 				count++
 			}
 		} else if action == "down" {
-			return scaleDown(hostScalingGroup, config, apiClient)
+			return scaleDown(hostScalingGroup, config, apiClient, job)
 		}
 	} else { // logic for scale host with labels
 		httpClient := &http.Client{
@@ -231,7 +257,7 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 			Filters: filters,
 		})
 		if len(hostCollection.Data) == 0 {
-			return http.StatusBadRequest, fmt.Errorf("No hosts for scaling found")
+			return false, http.StatusBadRequest, fmt.Errorf("No hosts for scaling found")
 		}
 
 		hostScalingGroup := []client.Host{}
@@ -268,11 +294,11 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 		}
 
 		if hostSelectorPresent == false {
-			return http.StatusBadRequest, fmt.Errorf("No host with label %v exists", hostSelector)
+			return false, http.StatusBadRequest, fmt.Errorf("No host with label %v exists", hostSelector)
 		}
 
 		if baseHostIndex == -1 && action == "up" {
-			return http.StatusBadRequest, fmt.Errorf("Cannot use custom hosts for scaling up")
+			return false, http.StatusBadRequest, fmt.Errorf("Cannot use custom hosts for scaling up")
 		}
 
 		count = 0
@@ -298,14 +324,17 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 
 			hostRaw, err := getHosts(getURL, httpClient, cattleConfig.CattleAccessKey, cattleConfig.CattleSecretKey)
 			if err != nil {
-				return http.StatusInternalServerError, err
+				return false, http.StatusInternalServerError, err
 			}
 
 			hostCreateURL := cattleURL + "/projects/" + host.AccountId + "/hosts"
-			newHostScale = amount + int64(len(hostScalingGroup))
-			if newHostScale > max {
-				return http.StatusBadRequest, fmt.Errorf("Cannot scale above provided max scale value")
+			current := int64(len(hostScalingGroup))
+			newHostScale, err = boundScale(current, current+amount, current, max, config.BoundsBehavior)
+			if err != nil {
+				return false, http.StatusBadRequest, err
 			}
+			change = &scaleChange{from: current, requested: current + amount, to: newHostScale}
+			amount = newHostScale - current
 
 			// Get the most recently created host with same prefix as base host, this will have largest suffix
 			suffix = ""
@@ -330,7 +359,7 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 				if suffix != "" {
 					prevNumber, err := strconv.Atoi(suffix)
 					if err != nil {
-						return http.StatusInternalServerError, fmt.Errorf("Error converting %s to int in scaleHost driver: %v", suffix, err)
+						return count > 0, http.StatusInternalServerError, fmt.Errorf("Error converting %s to int in scaleHost driver: %v", suffix, err)
 					}
 					currNumber := prevNumber + 1
 					currNameSuffix = leftPad(strconv.Itoa(currNumber), "0", len(suffix))
@@ -346,29 +375,38 @@ func (s *ScaleHostDriver) Execute(conf interface{}, apiClient *client.RancherCli
 				code, err := createHost(hostRaw, hostCreateURL, httpClient, cattleConfig.CattleAccessKey, cattleConfig.CattleSecretKey)
 				if err != nil {
 					log.Errorf("Cannot create host: %v", err)
-					return code, fmt.Errorf("Cannot create host")
+					return count > 0, code, fmt.Errorf("Cannot create host")
 				}
 
 				suffix = currNameSuffix
 				count++
 			}
 		} else if action == "down" {
-			return scaleDown(hostScalingGroup, config, apiClient)
+			return scaleDown(hostScalingGroup, config, apiClient, job)
 		}
 	}
 
-	return http.StatusOK, nil
+	if change != nil {
+		job.SetMessage(change.String())
+	}
+	return count > 0, http.StatusOK, nil
 }
 
-func scaleDown(hostScalingGroup []client.Host, config *model.ScaleHost, apiClient *client.RancherClient) (int, error) {
+func scaleDown(hostScalingGroup []client.Host, config *model.ScaleHost, apiClient *client.RancherClient, job *Job) (bool, int, error) {
 	amount := config.Amount
 	min := config.Min
 	deleteOption := config.DeleteOption
 
-	var newHostScale int64
-	newHostScale = int64(len(hostScalingGroup)) - amount
-	if newHostScale < min {
-		return http.StatusBadRequest, fmt.Errorf("Cannot scale below provided min scale value")
+	current := int64(len(hostScalingGroup))
+	newHostScale, err := boundScale(current, current-amount, min, current, config.BoundsBehavior)
+	if err != nil {
+		return false, http.StatusBadRequest, err
+	}
+	change := scaleChange{from: current, requested: current - amount, to: newHostScale}
+	amount = current - newHostScale
+	if amount == 0 {
+		job.SetMessage(change.String())
+		return false, http.StatusOK, nil
 	}
 
 	badHosts := make(map[string]bool)
@@ -377,14 +415,14 @@ func scaleDown(hostScalingGroup []client.Host, config *model.ScaleHost, apiClien
 		state := host.State
 		if state == "inactive" || state == "deactivating" || state == "reconnecting" || state == "disconnected" {
 			if deleteCount >= amount {
-				return http.StatusBadRequest, fmt.Errorf("Cannot scale down exceed amount")
+				return deleteCount > 0, http.StatusBadRequest, fmt.Errorf("Cannot scale down exceed amount")
 			}
 			badHosts[host.Id] = true
 			log.Infof("Deleting host %s with priority because of bad state: %s", host.Id, host.State)
 			code, err := deleteHost(host.Id, apiClient)
 			if err != nil {
 				log.Errorf("Cannot delete host: %v", err)
-				return code, fmt.Errorf("Cannot delete host")
+				return deleteCount > 0, code, fmt.Errorf("Cannot delete host")
 			}
 			deleteCount++
 		}
@@ -405,7 +443,7 @@ func scaleDown(hostScalingGroup []client.Host, config *model.ScaleHost, apiClien
 			code, err := deleteHost(host.Id, apiClient)
 			if err != nil {
 				log.Errorf("Cannot delete host: %v", err)
-				return code, fmt.Errorf("Cannot delete host")
+				return deleteCount+count > 0, code, fmt.Errorf("Cannot delete host")
 			}
 			delIndex++
 			count++
@@ -424,13 +462,14 @@ func scaleDown(hostScalingGroup []client.Host, config *model.ScaleHost, apiClien
 			code, err := deleteHost(host.Id, apiClient)
 			if err != nil {
 				log.Errorf("Cannot delete host: %v", err)
-				return code, fmt.Errorf("Cannot delete host")
+				return deleteCount+count > 0, code, fmt.Errorf("Cannot delete host")
 			}
 			delIndex++
 			count++
 		}
 	}
-	return http.StatusOK, nil
+	job.SetMessage(change.String())
+	return deleteCount+count > 0, http.StatusOK, nil
 }
 
 func (s *ScaleHostDriver) ConvertToConfigAndSetOnWebhook(conf interface{}, webhook *model.Webhook) error {
@@ -459,6 +498,7 @@ func (s *ScaleHostDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Sch
 	scaleOptions := []string{"up", "down"}
 	deleteOptions := []string{"mostRecent", "leastRecent"}
 	minValue := int64(1)
+	zeroValue := int64(0)
 
 	action := schema.ResourceFields["action"]
 	action.Type = "enum"
@@ -480,6 +520,17 @@ func (s *ScaleHostDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Sch
 	deleteOption.Options = deleteOptions
 	schema.ResourceFields["deleteOption"] = deleteOption
 
+	boundsBehavior := schema.ResourceFields["boundsBehavior"]
+	boundsBehavior.Type = "enum"
+	boundsBehavior.Options = []string{BoundsBehaviorReject, BoundsBehaviorClamp}
+	boundsBehavior.Default = BoundsBehaviorReject
+	schema.ResourceFields["boundsBehavior"] = boundsBehavior
+
+	cooldownSeconds := schema.ResourceFields["cooldownSeconds"]
+	cooldownSeconds.Default = 0
+	cooldownSeconds.Min = &zeroValue
+	schema.ResourceFields["cooldownSeconds"] = cooldownSeconds
+
 	return schema
 }
 
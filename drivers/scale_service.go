@@ -2,8 +2,10 @@ package drivers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	v1client "github.com/rancher/go-rancher/client"
@@ -11,6 +13,15 @@ import (
 	"github.com/rancher/webhook-service/model"
 )
 
+const (
+	scaleActionUp   = "up"
+	scaleActionDown = "down"
+	scaleActionSet  = "set"
+
+	amountTypeAbsolute   = "absolute"
+	amountTypePercentage = "percentage"
+)
+
 type ScaleServiceDriver struct {
 }
 
@@ -24,18 +35,67 @@ func (s *ScaleServiceDriver) ValidatePayload(conf interface{}, apiClient *client
 		return http.StatusBadRequest, fmt.Errorf("Scale action not provided")
 	}
 
-	if config.ScaleAction != "up" && config.ScaleAction != "down" {
+	if config.ScaleAction != scaleActionUp && config.ScaleAction != scaleActionDown && config.ScaleAction != scaleActionSet {
 		return http.StatusBadRequest, fmt.Errorf("Invalid action %v", config.ScaleAction)
 	}
 
-	if config.ScaleChange <= 0 {
-		return http.StatusBadRequest, fmt.Errorf("Invalid amount: %v", config.ScaleChange)
+	if config.AmountType != "" && config.AmountType != amountTypeAbsolute && config.AmountType != amountTypePercentage {
+		return http.StatusBadRequest, fmt.Errorf("Invalid amountType %v", config.AmountType)
 	}
 
-	if config.ServiceID == "" {
+	if config.ScaleAction == scaleActionSet {
+		if config.AmountType == amountTypePercentage {
+			return http.StatusBadRequest, fmt.Errorf("amountType %v can't be used with action %v", amountTypePercentage, scaleActionSet)
+		}
+		if config.ValuePath == "" && config.ScaleChange <= 0 {
+			return http.StatusBadRequest, fmt.Errorf("Action %v requires amount or valuePath", scaleActionSet)
+		}
+		if config.ValuePath != "" && config.ScaleChange != 0 {
+			return http.StatusBadRequest, fmt.Errorf("Only one of amount and valuePath can be provided")
+		}
+	} else {
+		if config.ValuePath != "" {
+			return http.StatusBadRequest, fmt.Errorf("valuePath can only be used with action %v", scaleActionSet)
+		}
+		if config.ScaleChange <= 0 {
+			return http.StatusBadRequest, fmt.Errorf("Invalid amount: %v", config.ScaleChange)
+		}
+	}
+
+	if config.ServiceID == "" && len(config.ServiceSelector) == 0 {
 		return http.StatusBadRequest, fmt.Errorf("ServiceId not provided")
 	}
 
+	if config.ServiceID != "" && len(config.ServiceSelector) > 0 {
+		return http.StatusBadRequest, fmt.Errorf("Only one of serviceId and serviceSelector can be provided")
+	}
+
+	if config.ServiceID == "" {
+		if err := validateSelector("serviceSelector", config.ServiceSelector); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	if config.PayloadFormat != "" && config.PayloadFormat != PayloadFormatNone && config.PayloadFormat != PayloadFormatAlertmanager {
+		return http.StatusBadRequest, fmt.Errorf("Invalid payloadFormat %v", config.PayloadFormat)
+	}
+
+	if config.ReverseOnResolved && config.PayloadFormat != PayloadFormatAlertmanager {
+		return http.StatusBadRequest, fmt.Errorf("reverseOnResolved requires payloadFormat %v", PayloadFormatAlertmanager)
+	}
+
+	if config.ReverseOnResolved && config.ScaleAction == scaleActionSet {
+		return http.StatusBadRequest, fmt.Errorf("reverseOnResolved can't be used with action %v", scaleActionSet)
+	}
+
+	if code, err := validateBoundsBehavior(config.BoundsBehavior); err != nil {
+		return code, err
+	}
+
+	if config.CooldownSeconds < 0 {
+		return http.StatusBadRequest, fmt.Errorf("Invalid cooldownSeconds: %v", config.CooldownSeconds)
+	}
+
 	if config.Min <= 0 {
 		return http.StatusBadRequest, fmt.Errorf("Minimum scale not provided/invalid")
 	}
@@ -48,6 +108,11 @@ func (s *ScaleServiceDriver) ValidatePayload(conf interface{}, apiClient *client
 		return http.StatusBadRequest, fmt.Errorf("Max must be greater than min")
 	}
 
+	//services matching a selector are resolved when the webhook is executed
+	if config.ServiceID == "" {
+		return http.StatusOK, nil
+	}
+
 	service, err := apiClient.Service.ById(config.ServiceID)
 	if err != nil {
 		return http.StatusInternalServerError, errors.Wrap(err, "Error in getService")
@@ -57,21 +122,34 @@ func (s *ScaleServiceDriver) ValidatePayload(conf interface{}, apiClient *client
 		return http.StatusBadRequest, fmt.Errorf("Invalid service %v", config.ServiceID)
 	}
 
+	if err := checkScalable(service); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return http.StatusOK, nil
+}
+
+//checkScalable returns why the scale of service can't be changed by a webhook
+func checkScalable(service *client.Service) error {
 	if service.Kind != "service" && service.Kind != "loadBalancerService" {
-		return http.StatusBadRequest, fmt.Errorf("Can only create webhooks for Services. The supplied service is of type %v", service.Kind)
+		return fmt.Errorf("Can only create webhooks for Services. The supplied service is of type %v", service.Kind)
+	}
+
+	if service.LaunchConfig == nil {
+		return nil
 	}
 
 	if val, ok := service.LaunchConfig.Labels["io.rancher.scheduler.global"]; ok {
 		if val == "true" {
-			return http.StatusBadRequest, fmt.Errorf("Cannot create webhook for global service %s", config.ServiceID)
+			return fmt.Errorf("Cannot create webhook for global service %s", service.Id)
 		}
 	}
 
 	if service.LaunchConfig.ImageUuid == "docker:rancher/none" {
-		return http.StatusBadRequest, fmt.Errorf("Cannot create webhook for service with no image %s", config.ServiceID)
+		return fmt.Errorf("Cannot create webhook for service with no image %s", service.Id)
 	}
 
-	return http.StatusOK, nil
+	return nil
 }
 
 func (s *ScaleServiceDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
@@ -80,45 +158,190 @@ func (s *ScaleServiceDriver) Execute(conf interface{}, apiClient *client.Rancher
 	if err != nil {
 		return http.StatusInternalServerError, errors.Wrap(err, "Couldn't unmarshal config")
 	}
-	var newScale int64
-	serviceID := config.ServiceID
 	scaleAction := config.ScaleAction
-	scaleChange := config.ScaleChange
-	min := config.Min
-	max := config.Max
+	job := JobFromRequest(request)
 
-	service, err := apiClient.Service.ById(serviceID)
+	payload, err := readRequestBody(request)
 	if err != nil {
-		return http.StatusInternalServerError, errors.Wrap(err, "Error in getService")
+		return http.StatusInternalServerError, err
 	}
 
-	if service == nil || service.Removed != "" {
-		return http.StatusBadRequest, fmt.Errorf("Service %v has been deleted", config.ServiceID)
+	var notification *AlertmanagerNotification
+	if config.PayloadFormat == PayloadFormatAlertmanager {
+		notification, err = ParseAlertmanagerNotification(payload)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+
+		if alertGroups.isDuplicate(job.ReceiverID(), notification) {
+			job.SetMessage(fmt.Sprintf("Alert group %s was already handled as %s, skipping scale", notification.GroupKey, notification.Status))
+			return http.StatusOK, nil
+		}
+
+		if notification.Status == alertStatusResolved {
+			if !config.ReverseOnResolved {
+				alertGroups.handled(job.ReceiverID(), notification)
+				job.SetMessage("Alert resolved, skipping scale")
+				return http.StatusOK, nil
+			}
+			scaleAction = reverseScaleAction(scaleAction)
+		}
+	}
+
+	if remaining := cooldowns.reserve(apiClient, job.ReceiverID(), config.CooldownSeconds); remaining > 0 {
+		log.Infof("Receiver %s is cooling down for another %v, skipping scale", job.ReceiverID(), remaining)
+		job.SetMessage(skippedCoolingDown)
+		return http.StatusOK, nil
+	}
+	scaledAny := false
+	defer func() {
+		cooldowns.release(apiClient, job.ReceiverID(), scaledAny)
+	}()
+
+	if config.ServiceID != "" {
+		service, err := apiClient.Service.ById(config.ServiceID)
+		if err != nil {
+			return http.StatusInternalServerError, errors.Wrap(err, "Error in getService")
+		}
+
+		if service == nil || service.Removed != "" {
+			return http.StatusBadRequest, fmt.Errorf("Service %v has been deleted", config.ServiceID)
+		}
+
+		change, code, err := scaleTarget(config, scaleAction, service.Scale, payload)
+		if err != nil {
+			return code, err
+		}
+
+		scaledAny, code, err = scaleService(apiClient, service, change, job)
+		if err != nil {
+			return code, err
+		}
+		if notification != nil {
+			alertGroups.handled(job.ReceiverID(), notification)
+		}
+		return http.StatusOK, nil
+	}
+
+	services, err := selectServices(apiClient, config.ServiceSelector)
+	if err != nil {
+		return http.StatusInternalServerError, err
 	}
 
-	if scaleAction == "up" {
-		newScale = service.Scale + scaleChange
-		if newScale > max {
-			return http.StatusBadRequest, fmt.Errorf("Cannot scale above provided max scale value")
+	//every matching service is scaled on its own, failures are reported per service
+	matched := 0
+	for i := range services {
+		service := &services[i]
+		if err := checkScalable(service); err != nil {
+			log.Infof("Skipping service %s matching serviceSelector: %v", service.Id, err)
+			continue
 		}
-	} else if scaleAction == "down" {
-		newScale = service.Scale - scaleChange
-		if newScale < min {
-			return http.StatusBadRequest, fmt.Errorf("Cannot scale below provided min scale value")
+		matched++
+
+		change, _, err := scaleTarget(config, scaleAction, service.Scale, payload)
+		if err != nil {
+			job.SetResult(service.Id, ResultStateError, err.Error())
+			continue
 		}
-	} else {
-		return http.StatusBadRequest, fmt.Errorf("Scale action not provided")
+
+		scaled, _, err := scaleService(apiClient, service, change, job)
+		if err != nil {
+			job.SetResult(service.Id, ResultStateError, err.Error())
+			continue
+		}
+		scaledAny = scaledAny || scaled
+	}
+
+	if matched == 0 {
+		job.SetMessage(fmt.Sprintf("No services matched serviceSelector %v", config.ServiceSelector))
+	}
+	if notification != nil {
+		alertGroups.handled(job.ReceiverID(), notification)
+	}
+	return http.StatusOK, nil
+}
+
+//scaleService applies change to service and records the result on job, it reports whether the scale was changed
+func scaleService(apiClient *client.RancherClient, service *client.Service, change scaleChange, job *Job) (bool, int, error) {
+	if change.to == change.from {
+		job.SetResult(service.Id, ResultStateUnchanged, change.String())
+		return false, http.StatusOK, nil
 	}
 
-	service, err = apiClient.Service.Update(service, client.Service{
-		Scale:        newScale,
-		CurrentScale: newScale,
+	_, err := apiClient.Service.Update(service, client.Service{
+		Scale:        change.to,
+		CurrentScale: change.to,
 	})
 	if err != nil {
-		statusCode := err.(*client.ApiError).StatusCode
-		return statusCode, errors.Wrap(err, "Error in updateService")
+		statusCode := http.StatusInternalServerError
+		if apiErr, ok := err.(*client.ApiError); ok {
+			statusCode = apiErr.StatusCode
+		}
+		return false, statusCode, errors.Wrap(err, "Error in updateService")
 	}
-	return http.StatusOK, nil
+	job.SetResult(service.Id, ResultStateScaled, change.String())
+	return true, http.StatusOK, nil
+}
+
+//scaleTarget computes the new scale of a service, a target outside min and max is handled as boundsBehavior says
+func scaleTarget(config *model.ScaleService, action string, current int64, payload []byte) (scaleChange, int, error) {
+	change := scaleChange{from: current}
+	switch action {
+	case scaleActionSet:
+		change.requested = config.ScaleChange
+		if config.ValuePath != "" {
+			value, err := lookupCount(payload, config.ValuePath)
+			if err != nil {
+				return change, http.StatusBadRequest, err
+			}
+			change.requested = value
+		}
+		to, err := boundScale(current, change.requested, config.Min, config.Max, config.BoundsBehavior)
+		if err != nil {
+			return change, http.StatusBadRequest, err
+		}
+		change.to = to
+		return change, http.StatusOK, nil
+	case scaleActionUp, scaleActionDown:
+		amount := config.ScaleChange
+		if config.AmountType == amountTypePercentage {
+			amount = percentageChange(current, amount)
+		}
+		if action == scaleActionDown {
+			amount = -amount
+		}
+		//only the bound in the direction of the change applies
+		min, max := config.Min, config.Max
+		if amount > 0 {
+			min = current
+		} else {
+			max = current
+		}
+		change.requested = current + amount
+		to, err := boundScale(current, change.requested, min, max, config.BoundsBehavior)
+		if err != nil {
+			return change, http.StatusBadRequest, err
+		}
+		change.to = to
+		return change, http.StatusOK, nil
+	}
+	return change, http.StatusBadRequest, fmt.Errorf("Scale action not provided")
+}
+
+//percentageChange returns percent of current rounded up, changing the scale by at least 1
+func percentageChange(current int64, percent int64) int64 {
+	change := int64(math.Ceil(float64(current) * float64(percent) / 100))
+	if change < 1 {
+		return 1
+	}
+	return change
+}
+
+func reverseScaleAction(action string) string {
+	if action == scaleActionUp {
+		return scaleActionDown
+	}
+	return scaleActionUp
 }
 
 func (s *ScaleServiceDriver) ConvertToConfigAndSetOnWebhook(conf interface{}, webhook *model.Webhook) error {
@@ -144,14 +367,23 @@ func (s *ScaleServiceDriver) GetDriverConfigResource() interface{} {
 }
 
 func (s *ScaleServiceDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Schema {
-	options := []string{"up", "down"}
+	options := []string{scaleActionUp, scaleActionDown, scaleActionSet}
 	minValue := int64(1)
+	zeroValue := int64(0)
+
+	describeSelector(schema)
 
 	action := schema.ResourceFields["action"]
 	action.Type = "enum"
 	action.Options = options
 	schema.ResourceFields["action"] = action
 
+	amountType := schema.ResourceFields["amountType"]
+	amountType.Type = "enum"
+	amountType.Options = []string{amountTypeAbsolute, amountTypePercentage}
+	amountType.Default = amountTypeAbsolute
+	schema.ResourceFields["amountType"] = amountType
+
 	min := schema.ResourceFields["min"]
 	min.Default = 1
 	min.Min = &minValue
@@ -162,5 +394,22 @@ func (s *ScaleServiceDriver) CustomizeSchema(schema *v1client.Schema) *v1client.
 	max.Min = &minValue
 	schema.ResourceFields["max"] = max
 
+	boundsBehavior := schema.ResourceFields["boundsBehavior"]
+	boundsBehavior.Type = "enum"
+	boundsBehavior.Options = []string{BoundsBehaviorReject, BoundsBehaviorClamp}
+	boundsBehavior.Default = BoundsBehaviorReject
+	schema.ResourceFields["boundsBehavior"] = boundsBehavior
+
+	cooldownSeconds := schema.ResourceFields["cooldownSeconds"]
+	cooldownSeconds.Default = 0
+	cooldownSeconds.Min = &zeroValue
+	schema.ResourceFields["cooldownSeconds"] = cooldownSeconds
+
+	payloadFormat := schema.ResourceFields["payloadFormat"]
+	payloadFormat.Type = "enum"
+	payloadFormat.Options = []string{PayloadFormatNone, PayloadFormatAlertmanager}
+	payloadFormat.Default = PayloadFormatNone
+	schema.ResourceFields["payloadFormat"] = payloadFormat
+
 	return schema
 }
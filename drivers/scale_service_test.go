@@ -0,0 +1,226 @@
+package drivers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestLookupCount(t *testing.T) {
+	tests := []struct {
+		payload  string
+		path     string
+		expected int64
+		valid    bool
+	}{
+		{`{"replicas": 4}`, "replicas", 4, true},
+		{`{"alerts": [{"value": "2.5"}]}`, "alerts.0.value", 3, true},
+		{`{"load": 0.1}`, "load", 1, true},
+		{`{"load": 0}`, "load", 0, true},
+		{`{"replicas": " 7 "}`, "replicas", 7, true},
+		{`{"replicas": -1}`, "replicas", 0, false},
+		{`{"replicas": "many"}`, "replicas", 0, false},
+		{`{"replicas": true}`, "replicas", 0, false},
+		{`{"replicas": 1e12}`, "replicas", 0, false},
+		{`{"replicas": 4}`, "count", 0, false},
+		{`{"alerts": []}`, "alerts.0.value", 0, false},
+		{`not json`, "replicas", 0, false},
+	}
+
+	for _, test := range tests {
+		count, err := lookupCount([]byte(test.payload), test.path)
+		if !test.valid {
+			if err == nil {
+				t.Fatalf("Expected error for %s at %s, got %d", test.payload, test.path, count)
+			}
+			continue
+		}
+		if err != nil || count != test.expected {
+			t.Fatalf("Expected %d for %s at %s, got %d %v", test.expected, test.payload, test.path, count, err)
+		}
+	}
+}
+
+func TestPercentageChange(t *testing.T) {
+	tests := []struct {
+		current  int64
+		percent  int64
+		expected int64
+	}{
+		{10, 50, 5},
+		{10, 25, 3},
+		{3, 10, 1},
+		{0, 50, 1},
+		{7, 100, 7},
+		{4, 150, 6},
+	}
+
+	for _, test := range tests {
+		if change := percentageChange(test.current, test.percent); change != test.expected {
+			t.Fatalf("%d%% of %d: expected %d, got %d", test.percent, test.current, test.expected, change)
+		}
+	}
+}
+
+func TestScaleTargetSetAndPercentage(t *testing.T) {
+	tests := []struct {
+		config   model.ScaleService
+		action   string
+		current  int64
+		payload  string
+		expected int64
+		valid    bool
+	}{
+		{model.ScaleService{ScaleChange: 5, Min: 1, Max: 10}, scaleActionSet, 2, "", 5, true},
+		{model.ScaleService{ScaleChange: 5, Min: 1, Max: 10, ValuePath: "replicas"}, scaleActionSet, 2, `{"replicas": 8}`, 8, true},
+		{model.ScaleService{Min: 1, Max: 10, ValuePath: "replicas"}, scaleActionSet, 2, `{"replicas": 2.2}`, 3, true},
+		//set and percentage follow boundsBehavior like the other actions
+		{model.ScaleService{Min: 2, Max: 10, ValuePath: "replicas", BoundsBehavior: BoundsBehaviorReject}, scaleActionSet, 4, `{"replicas": 40}`, 0, false},
+		{model.ScaleService{Min: 2, Max: 10, ValuePath: "replicas"}, scaleActionSet, 4, `{"replicas": 0}`, 0, false},
+		{model.ScaleService{Min: 2, Max: 10, ValuePath: "replicas", BoundsBehavior: BoundsBehaviorClamp}, scaleActionSet, 4, `{"replicas": 40}`, 10, true},
+		{model.ScaleService{Min: 2, Max: 10, ValuePath: "replicas", BoundsBehavior: BoundsBehaviorClamp}, scaleActionSet, 4, `{"replicas": 0}`, 2, true},
+		{model.ScaleService{Min: 1, Max: 10, ValuePath: "replicas"}, scaleActionSet, 2, `{"count": 8}`, 0, false},
+		{model.ScaleService{Min: 1, Max: 10, ValuePath: "replicas"}, scaleActionSet, 2, ``, 0, false},
+		{model.ScaleService{ScaleChange: 50, AmountType: amountTypePercentage, Min: 1, Max: 20}, scaleActionUp, 5, "", 8, true},
+		{model.ScaleService{ScaleChange: 50, AmountType: amountTypePercentage, Min: 1, Max: 20}, scaleActionDown, 5, "", 2, true},
+		{model.ScaleService{ScaleChange: 10, AmountType: amountTypePercentage, Min: 1, Max: 20}, scaleActionDown, 3, "", 2, true},
+		{model.ScaleService{ScaleChange: 200, AmountType: amountTypePercentage, Min: 1, Max: 20}, scaleActionUp, 10, "", 0, false},
+		{model.ScaleService{ScaleChange: 100, AmountType: amountTypePercentage, Min: 2, Max: 20, BoundsBehavior: BoundsBehaviorReject}, scaleActionDown, 6, "", 0, false},
+		{model.ScaleService{ScaleChange: 200, AmountType: amountTypePercentage, Min: 1, Max: 20, BoundsBehavior: BoundsBehaviorClamp}, scaleActionUp, 10, "", 20, true},
+		{model.ScaleService{ScaleChange: 100, AmountType: amountTypePercentage, Min: 2, Max: 20, BoundsBehavior: BoundsBehaviorClamp}, scaleActionDown, 6, "", 2, true},
+		{model.ScaleService{ScaleChange: 1, Min: 1, Max: 20}, "", 6, "", 0, false},
+	}
+
+	for i, test := range tests {
+		change, code, err := scaleTarget(&test.config, test.action, test.current, []byte(test.payload))
+		if !test.valid {
+			if code != http.StatusBadRequest || err == nil {
+				t.Fatalf("Case %d: expected bad request, got %d %v", i, code, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Case %d: unexpected %d %v", i, code, err)
+		}
+		if change.from != test.current || change.to != test.expected {
+			t.Fatalf("Case %d: expected %d to %d, got %#v", i, test.current, test.expected, change)
+		}
+	}
+}
+
+func TestScaleServiceSetFromPayload(t *testing.T) {
+	apiClient, services := newFakeClient(client.Service{Resource: client.Resource{Id: "1s1"}, Scale: 2})
+	config := configMap(t, model.ScaleService{
+		ServiceID:   "1s1",
+		ScaleAction: scaleActionSet,
+		Min:         1,
+		Max:         10,
+		ValuePath:   "queue.consumers",
+	})
+	request, job := jobRequest(t, "1wr-set", `{"queue": {"consumers": 6}}`)
+	if code, err := (&ScaleServiceDriver{}).Execute(config, apiClient, request); code != http.StatusOK || err != nil {
+		t.Fatalf("Unexpected %d %v", code, err)
+	}
+	if scale := services.scale("1s1"); scale != 6 {
+		t.Fatalf("Expected scale 6, got %d", scale)
+	}
+	if state, message := result(job, "1s1"); state != ResultStateScaled || message != "Scaled from 2 to 6" {
+		t.Fatalf("Unexpected result %s %q", state, message)
+	}
+}
+
+func TestScaleServiceBoundsBehavior(t *testing.T) {
+	tests := []struct {
+		behavior string
+		code     int
+		scale    int64
+		state    string
+	}{
+		{BoundsBehaviorReject, http.StatusBadRequest, 9, ""},
+		{BoundsBehaviorClamp, http.StatusOK, 10, ResultStateScaled},
+	}
+
+	for _, test := range tests {
+		apiClient, services := newFakeClient(client.Service{Resource: client.Resource{Id: "1s1"}, Scale: 9})
+		config := configMap(t, model.ScaleService{
+			ServiceID:      "1s1",
+			ScaleAction:    scaleActionUp,
+			ScaleChange:    3,
+			Min:            1,
+			Max:            10,
+			BoundsBehavior: test.behavior,
+		})
+		request, job := jobRequest(t, "1wr-bounds", "")
+		if code, _ := (&ScaleServiceDriver{}).Execute(config, apiClient, request); code != test.code {
+			t.Fatalf("%s: expected %d, got %d", test.behavior, test.code, code)
+		}
+		if scale := services.scale("1s1"); scale != test.scale {
+			t.Fatalf("%s: expected scale %d, got %d", test.behavior, test.scale, scale)
+		}
+		if state, _ := result(job, "1s1"); state != test.state {
+			t.Fatalf("%s: expected result %q, got %q", test.behavior, test.state, state)
+		}
+	}
+}
+
+func TestScaleServiceSelectorResults(t *testing.T) {
+	labeled := func(id string, scale int64, labels map[string]interface{}) client.Service {
+		return client.Service{
+			Resource:     client.Resource{Id: id},
+			Scale:        scale,
+			LaunchConfig: &client.LaunchConfig{Labels: labels},
+		}
+	}
+	apiClient, services := newFakeClient(
+		labeled("1s1", 2, map[string]interface{}{"tier": "web"}),
+		labeled("1s2", 10, map[string]interface{}{"tier": "web"}),
+		labeled("1s3", 2, map[string]interface{}{"tier": "db"}),
+		labeled("1s4", 2, map[string]interface{}{"tier": "web", "io.rancher.scheduler.global": "true"}),
+		labeled("1s5", 4, map[string]interface{}{"tier": "web"}),
+	)
+	config := configMap(t, model.ScaleService{
+		ServiceSelector: map[string]string{"tier": "web"},
+		ScaleAction:     scaleActionUp,
+		ScaleChange:     2,
+		Min:             1,
+		Max:             5,
+	})
+	request, job := jobRequest(t, "1wr-selector", "")
+	if code, err := (&ScaleServiceDriver{}).Execute(config, apiClient, request); code != http.StatusOK || err != nil {
+		t.Fatalf("Unexpected %d %v", code, err)
+	}
+
+	expected := map[string]struct {
+		scale int64
+		state string
+	}{
+		"1s1": {4, ResultStateScaled},
+		//out of bounds on one service doesn't stop the others
+		"1s2": {10, ResultStateError},
+		"1s3": {2, ""},
+		"1s4": {2, ""},
+		"1s5": {4, ResultStateError},
+	}
+	for id, e := range expected {
+		if scale := services.scale(id); scale != e.scale {
+			t.Fatalf("Service %s: expected scale %d, got %d", id, e.scale, scale)
+		}
+		if state, message := result(job, id); state != e.state {
+			t.Fatalf("Service %s: expected result %q, got %q %q", id, e.state, state, message)
+		}
+	}
+	if message := job.Resource().Message; message != "" {
+		t.Fatalf("Unexpected job message %q", message)
+	}
+
+	request, job = jobRequest(t, "1wr-selector", "")
+	config["serviceSelector"] = map[string]interface{}{"tier": "cache"}
+	if code, err := (&ScaleServiceDriver{}).Execute(config, apiClient, request); code != http.StatusOK || err != nil {
+		t.Fatalf("Unexpected %d %v", code, err)
+	}
+	if message := job.Resource().Message; message != "No services matched serviceSelector map[tier:cache]" {
+		t.Fatalf("Unexpected job message %q", message)
+	}
+}
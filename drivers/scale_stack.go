@@ -0,0 +1,197 @@
+package drivers
+
+import (
+	"fmt"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	v1client "github.com/rancher/go-rancher/client"
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+type ScaleStackDriver struct {
+}
+
+func (s *ScaleStackDriver) ValidatePayload(conf interface{}, apiClient *client.RancherClient) (int, error) {
+	config, ok := conf.(model.ScaleStack)
+	if !ok {
+		return http.StatusInternalServerError, fmt.Errorf("Can't process config")
+	}
+
+	if config.ScaleAction == "" {
+		return http.StatusBadRequest, fmt.Errorf("Scale action not provided")
+	}
+
+	if config.ScaleAction != scaleActionUp && config.ScaleAction != scaleActionDown {
+		return http.StatusBadRequest, fmt.Errorf("Invalid action %v", config.ScaleAction)
+	}
+
+	if config.AmountType != "" && config.AmountType != amountTypeAbsolute && config.AmountType != amountTypePercentage {
+		return http.StatusBadRequest, fmt.Errorf("Invalid amountType %v", config.AmountType)
+	}
+
+	if config.ScaleChange <= 0 {
+		return http.StatusBadRequest, fmt.Errorf("Invalid amount: %v", config.ScaleChange)
+	}
+
+	if config.StackID == "" {
+		return http.StatusBadRequest, fmt.Errorf("StackId not provided")
+	}
+
+	if code, err := validateBoundsBehavior(config.BoundsBehavior); err != nil {
+		return code, err
+	}
+
+	if config.Min <= 0 {
+		return http.StatusBadRequest, fmt.Errorf("Minimum scale not provided/invalid")
+	}
+
+	if config.Max <= 0 {
+		return http.StatusBadRequest, fmt.Errorf("Maximum scale not provided/invalid")
+	}
+
+	if config.Min >= config.Max {
+		return http.StatusBadRequest, fmt.Errorf("Max must be greater than min")
+	}
+
+	stack, err := apiClient.Stack.ById(config.StackID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "Error in getStack")
+	}
+
+	if stack == nil || stack.Removed != "" {
+		return http.StatusBadRequest, fmt.Errorf("Invalid stack %v", config.StackID)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (s *ScaleStackDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
+	config := &model.ScaleStack{}
+	err := mapstructure.Decode(conf, config)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "Couldn't unmarshal config")
+	}
+	job := JobFromRequest(request)
+
+	stack, err := apiClient.Stack.ById(config.StackID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "Error in getStack")
+	}
+
+	if stack == nil || stack.Removed != "" {
+		return http.StatusBadRequest, fmt.Errorf("Stack %v has been deleted", config.StackID)
+	}
+
+	filters := make(map[string]interface{})
+	filters["stackId"] = config.StackID
+	services, err := apiClient.Service.List(&client.ListOpts{
+		Filters: filters,
+	})
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "Error in listing services")
+	}
+
+	//the services of the stack share the scaling options of a scaleService webhook
+	serviceConfig := &model.ScaleService{
+		ScaleAction:    config.ScaleAction,
+		ScaleChange:    config.ScaleChange,
+		AmountType:     config.AmountType,
+		Min:            config.Min,
+		Max:            config.Max,
+		BoundsBehavior: config.BoundsBehavior,
+	}
+
+	//every target is computed before scaling, so that a service out of bounds rejects the whole stack.
+	//Clamped targets never fail and the services are scaled on their own
+	type stackTarget struct {
+		service *client.Service
+		change  scaleChange
+	}
+	targets := []stackTarget{}
+	for i := range services.Data {
+		service := &services.Data[i]
+		if service.Removed != "" || service.StackId != config.StackID {
+			continue
+		}
+		if err := checkScalable(service); err != nil {
+			log.Infof("Skipping service %s of stack %s: %v", service.Id, config.StackID, err)
+			continue
+		}
+
+		change, code, err := scaleTarget(serviceConfig, config.ScaleAction, service.Scale, nil)
+		if err != nil {
+			job.SetResult(service.Id, ResultStateError, err.Error())
+			return code, fmt.Errorf("Scale of stack %s rejected, service %s: %v", config.StackID, service.Id, err)
+		}
+		targets = append(targets, stackTarget{service: service, change: change})
+	}
+
+	if len(targets) == 0 {
+		job.SetMessage(fmt.Sprintf("Stack %s has no scalable services", config.StackID))
+	}
+	for _, target := range targets {
+		if _, _, err := scaleService(apiClient, target.service, target.change, job); err != nil {
+			job.SetResult(target.service.Id, ResultStateError, err.Error())
+		}
+	}
+	return http.StatusOK, nil
+}
+
+func (s *ScaleStackDriver) ConvertToConfigAndSetOnWebhook(conf interface{}, webhook *model.Webhook) error {
+	if scaleConfig, ok := conf.(model.ScaleStack); ok {
+		webhook.ScaleStackConfig = scaleConfig
+		webhook.ScaleStackConfig.Type = webhook.Driver
+		return nil
+	} else if configMap, ok := conf.(map[string]interface{}); ok {
+		config := model.ScaleStack{}
+		err := mapstructure.Decode(configMap, &config)
+		if err != nil {
+			return err
+		}
+		webhook.ScaleStackConfig = config
+		webhook.ScaleStackConfig.Type = webhook.Driver
+		return nil
+	}
+	return fmt.Errorf("Can't convert config %v", conf)
+}
+
+func (s *ScaleStackDriver) GetDriverConfigResource() interface{} {
+	return model.ScaleStack{}
+}
+
+func (s *ScaleStackDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Schema {
+	minValue := int64(1)
+
+	action := schema.ResourceFields["action"]
+	action.Type = "enum"
+	action.Options = []string{scaleActionUp, scaleActionDown}
+	schema.ResourceFields["action"] = action
+
+	amountType := schema.ResourceFields["amountType"]
+	amountType.Type = "enum"
+	amountType.Options = []string{amountTypeAbsolute, amountTypePercentage}
+	amountType.Default = amountTypeAbsolute
+	schema.ResourceFields["amountType"] = amountType
+
+	boundsBehavior := schema.ResourceFields["boundsBehavior"]
+	boundsBehavior.Type = "enum"
+	boundsBehavior.Options = []string{BoundsBehaviorReject, BoundsBehaviorClamp}
+	boundsBehavior.Default = BoundsBehaviorReject
+	schema.ResourceFields["boundsBehavior"] = boundsBehavior
+
+	min := schema.ResourceFields["min"]
+	min.Default = 1
+	min.Min = &minValue
+	schema.ResourceFields["min"] = min
+
+	max := schema.ResourceFields["max"]
+	max.Default = 100
+	max.Min = &minValue
+	schema.ResourceFields["max"] = max
+
+	return schema
+}
@@ -0,0 +1,151 @@
+package drivers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+type fakeStacks struct {
+	client.StackOperations
+	stacks map[string]*client.Stack
+}
+
+func (f *fakeStacks) ById(id string) (*client.Stack, error) {
+	stack, ok := f.stacks[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *stack
+	return &copied, nil
+}
+
+func TestScaleStackSkipsUnscalableServices(t *testing.T) {
+	inStack := func(id string, stackID string, scale int64, launchConfig *client.LaunchConfig) client.Service {
+		return client.Service{
+			Resource:     client.Resource{Id: id},
+			StackId:      stackID,
+			Scale:        scale,
+			LaunchConfig: launchConfig,
+		}
+	}
+	lb := inStack("1s2", "1st1", 2, nil)
+	lb.Kind = "loadBalancerService"
+	dns := inStack("1s3", "1st1", 1, nil)
+	dns.Kind = "dnsService"
+	removed := inStack("1s7", "1st1", 2, nil)
+	removed.Removed = "2017-01-01T00:00:00Z"
+
+	apiClient, services := newFakeClient(
+		inStack("1s1", "1st1", 2, &client.LaunchConfig{}),
+		lb,
+		dns,
+		inStack("1s4", "1st1", 2, &client.LaunchConfig{Labels: map[string]interface{}{"io.rancher.scheduler.global": "true"}}),
+		inStack("1s5", "1st1", 2, &client.LaunchConfig{ImageUuid: "docker:rancher/none"}),
+		inStack("1s6", "1st2", 2, &client.LaunchConfig{}),
+		removed,
+		inStack("1s8", "1st1", 3, &client.LaunchConfig{}),
+	)
+	apiClient.Stack = &fakeStacks{stacks: map[string]*client.Stack{
+		"1st1": {Resource: client.Resource{Id: "1st1"}},
+		"1st3": {Resource: client.Resource{Id: "1st3"}},
+	}}
+
+	config := configMap(t, model.ScaleStack{
+		StackID:     "1st1",
+		ScaleAction: scaleActionUp,
+		ScaleChange: 1,
+		Min:         1,
+		Max:         4,
+	})
+	request, job := jobRequest(t, "1wr-stack", "")
+	if code, err := (&ScaleStackDriver{}).Execute(config, apiClient, request); code != http.StatusOK || err != nil {
+		t.Fatalf("Unexpected %d %v", code, err)
+	}
+
+	expected := map[string]struct {
+		scale int64
+		state string
+	}{
+		"1s1": {3, ResultStateScaled},
+		"1s2": {3, ResultStateScaled},
+		"1s3": {1, ""},
+		"1s4": {2, ""},
+		"1s5": {2, ""},
+		"1s6": {2, ""},
+		"1s7": {2, ""},
+		"1s8": {4, ResultStateScaled},
+	}
+	for id, e := range expected {
+		if scale := services.scale(id); scale != e.scale {
+			t.Fatalf("Service %s: expected scale %d, got %d", id, e.scale, scale)
+		}
+		if state, message := result(job, id); state != e.state {
+			t.Fatalf("Service %s: expected result %q, got %q %q", id, e.state, state, message)
+		}
+	}
+
+	config["stackId"] = "1st3"
+	request, job = jobRequest(t, "1wr-stack", "")
+	if code, err := (&ScaleStackDriver{}).Execute(config, apiClient, request); code != http.StatusOK || err != nil {
+		t.Fatalf("Unexpected %d %v", code, err)
+	}
+	if message := job.Resource().Message; message != "Stack 1st3 has no scalable services" {
+		t.Fatalf("Unexpected job message %q", message)
+	}
+
+	config["stackId"] = "1st4"
+	request, _ = jobRequest(t, "1wr-stack", "")
+	if code, err := (&ScaleStackDriver{}).Execute(config, apiClient, request); code != http.StatusBadRequest || err == nil {
+		t.Fatalf("Expected bad request for a removed stack, got %d %v", code, err)
+	}
+}
+
+func TestScaleStackBoundsBehavior(t *testing.T) {
+	tests := []struct {
+		behavior string
+		code     int
+		scales   map[string]int64
+		states   map[string]string
+	}{
+		//one service at its bound rejects the whole stack
+		{BoundsBehaviorReject, http.StatusBadRequest,
+			map[string]int64{"1s1": 2, "1s2": 4},
+			map[string]string{"1s1": "", "1s2": ResultStateError}},
+		{BoundsBehaviorClamp, http.StatusOK,
+			map[string]int64{"1s1": 3, "1s2": 4},
+			map[string]string{"1s1": ResultStateScaled, "1s2": ResultStateUnchanged}},
+	}
+
+	for _, test := range tests {
+		apiClient, services := newFakeClient(
+			client.Service{Resource: client.Resource{Id: "1s1"}, StackId: "1st1", Scale: 2, LaunchConfig: &client.LaunchConfig{}},
+			client.Service{Resource: client.Resource{Id: "1s2"}, StackId: "1st1", Scale: 4, LaunchConfig: &client.LaunchConfig{}},
+		)
+		apiClient.Stack = &fakeStacks{stacks: map[string]*client.Stack{
+			"1st1": {Resource: client.Resource{Id: "1st1"}},
+		}}
+		config := configMap(t, model.ScaleStack{
+			StackID:        "1st1",
+			ScaleAction:    scaleActionUp,
+			ScaleChange:    1,
+			Min:            1,
+			Max:            4,
+			BoundsBehavior: test.behavior,
+		})
+		request, job := jobRequest(t, "1wr-stack-bounds", "")
+		if code, _ := (&ScaleStackDriver{}).Execute(config, apiClient, request); code != test.code {
+			t.Fatalf("%s: expected %d, got %d", test.behavior, test.code, code)
+		}
+		for id, scale := range test.scales {
+			if actual := services.scale(id); actual != scale {
+				t.Fatalf("%s: service %s expected scale %d, got %d", test.behavior, id, scale, actual)
+			}
+			if state, message := result(job, id); state != test.states[id] {
+				t.Fatalf("%s: service %s expected result %q, got %q %q", test.behavior, id, test.states[id], state, message)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package drivers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1client "github.com/rancher/go-rancher/client"
+	"github.com/rancher/go-rancher/v2"
+)
+
+const (
+	selectorEquals       = "="
+	selectorNotEquals    = "!="
+	selectorIn           = "in"
+	selectorNotIn        = "notin"
+	selectorExists       = "exists"
+	selectorDoesNotExist = "!exists"
+)
+
+//selectorExpressionPrefix marks a selector value as an expression, any other value is the literal label value
+const selectorExpressionPrefix = "expr:"
+
+const selectorDescription = `Labels to match, all entries must match. A value is the label value, or an expression when ` +
+	`prefixed with "expr:": "expr:=v", "expr:!=v", "expr:in (a,b)", "expr:notin (a,b)", "expr:exists" or "expr:!exists"`
+
+var regSelectorSet = regexp.MustCompile(`^(in|notin)\s*\((.*)\)$`)
+
+type labelRequirement struct {
+	key      string
+	operator string
+	values   []string
+}
+
+//parseSelector turns each selector entry into a requirement. A value is matched literally, as selectors always did,
+//an empty one matching an empty label. Values prefixed with "expr:" hold an expression instead:
+//"=v", "!=v", "in (a,b)", "notin (a,b)", "exists" or "!exists"
+func parseSelector(selector map[string]string) ([]labelRequirement, error) {
+	requirements := []labelRequirement{}
+	for key, value := range selector {
+		requirement := labelRequirement{key: strings.TrimSpace(key)}
+		if requirement.key == "" {
+			return nil, fmt.Errorf("empty label key")
+		}
+		if !strings.HasPrefix(value, selectorExpressionPrefix) {
+			requirement.operator = selectorEquals
+			requirement.values = []string{value}
+			requirements = append(requirements, requirement)
+			continue
+		}
+
+		expression := strings.TrimSpace(strings.TrimPrefix(value, selectorExpressionPrefix))
+		switch {
+		case expression == selectorExists, expression == selectorDoesNotExist:
+			requirement.operator = expression
+		case strings.HasPrefix(expression, selectorNotEquals):
+			requirement.operator = selectorNotEquals
+			requirement.values = []string{strings.TrimSpace(strings.TrimPrefix(expression, selectorNotEquals))}
+		case strings.HasPrefix(expression, selectorEquals):
+			requirement.operator = selectorEquals
+			requirement.values = []string{strings.TrimSpace(strings.TrimPrefix(expression, selectorEquals))}
+		case regSelectorSet.MatchString(expression):
+			match := regSelectorSet.FindStringSubmatch(expression)
+			requirement.operator = match[1]
+			for _, v := range strings.Split(match[2], ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					requirement.values = append(requirement.values, v)
+				}
+			}
+			if len(requirement.values) == 0 {
+				return nil, fmt.Errorf("Selector %s %s has no values", key, value)
+			}
+		default:
+			return nil, fmt.Errorf("Selector %s has invalid expression %s", key, value)
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+//matches reports whether labels satisfy the requirement, keys and values are compared case insensitively
+func (r labelRequirement) matches(labels map[string]interface{}) bool {
+	value, found := "", false
+	for k, v := range labels {
+		if strings.EqualFold(k, r.key) {
+			value, _ = v.(string)
+			found = true
+			break
+		}
+	}
+
+	switch r.operator {
+	case selectorExists:
+		return found
+	case selectorDoesNotExist:
+		return !found
+	case selectorEquals, selectorIn:
+		return found && containsFold(r.values, value)
+	case selectorNotEquals, selectorNotIn:
+		return !found || !containsFold(r.values, value)
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+//validateSelector checks a label selector is usable for matching
+func validateSelector(name string, selector map[string]string) error {
+	if len(selector) == 0 {
+		return fmt.Errorf("%s not provided", name)
+	}
+	if _, err := parseSelector(selector); err != nil {
+		return fmt.Errorf("%s is invalid: %v", name, err)
+	}
+	return nil
+}
+
+//describeSelector documents the selector syntax on the serviceSelector field of schema
+func describeSelector(schema *v1client.Schema) {
+	serviceSelector := schema.ResourceFields["serviceSelector"]
+	serviceSelector.Description = selectorDescription
+	schema.ResourceFields["serviceSelector"] = serviceSelector
+}
+
+//matchesSelector reports whether labels satisfy every requirement of selector
+func matchesSelector(selector map[string]string, labels map[string]interface{}) bool {
+	requirements, err := parseSelector(selector)
+	if err != nil || len(requirements) == 0 {
+		return false
+	}
+	for _, requirement := range requirements {
+		if !requirement.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+//selectServices lists the services of the project whose primary launch config matches selector
+func selectServices(apiClient *client.RancherClient, selector map[string]string) ([]client.Service, error) {
+	services, err := apiClient.Service.List(&client.ListOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("Error %v in listing services", err)
+	}
+	selected := []client.Service{}
+	for _, service := range services.Data {
+		if service.Removed != "" || service.LaunchConfig == nil {
+			continue
+		}
+		if matchesSelector(selector, service.LaunchConfig.Labels) {
+			selected = append(selected, service)
+		}
+	}
+	return selected, nil
+}
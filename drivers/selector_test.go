@@ -0,0 +1,123 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/rancher/go-rancher/v2"
+)
+
+func TestMatchesSelector(t *testing.T) {
+	labels := map[string]interface{}{"tier": "web", "env": "Prod", "owner": ""}
+
+	tests := []struct {
+		selector map[string]string
+		expected bool
+	}{
+		{map[string]string{"tier": "web"}, true},
+		{map[string]string{"TIER": "WEB"}, true},
+		{map[string]string{"tier": "web", "env": "prod"}, true},
+		{map[string]string{"tier": "web", "env": "staging"}, false},
+		//values without the expression prefix keep their literal meaning
+		{map[string]string{"owner": ""}, true},
+		{map[string]string{"tier": ""}, false},
+		{map[string]string{"zone": ""}, false},
+		{map[string]string{"tier": "=web"}, false},
+		{map[string]string{"tier": "!=db"}, false},
+		{map[string]string{"TIER": "expr:=WEB"}, true},
+		{map[string]string{"tier": "expr:!=db"}, true},
+		{map[string]string{"tier": "expr:!=web"}, false},
+		{map[string]string{"zone": "expr:!=east"}, true},
+		{map[string]string{"tier": "expr:in (api, web)"}, true},
+		{map[string]string{"tier": "expr:in (api,db)"}, false},
+		{map[string]string{"zone": "expr:in (east)"}, false},
+		{map[string]string{"tier": "expr:notin (api,db)"}, true},
+		{map[string]string{"tier": "expr:notin (web)"}, false},
+		{map[string]string{"zone": "expr:notin (east)"}, true},
+		{map[string]string{"tier": "expr:exists"}, true},
+		{map[string]string{"zone": "expr:exists"}, false},
+		{map[string]string{"zone": "expr:!exists"}, true},
+		{map[string]string{"tier": "expr:!exists"}, false},
+		{map[string]string{"tier": "expr:in (web)", "zone": "expr:!exists", "env": "expr:notin (staging)"}, true},
+		{map[string]string{"tier": "expr:in ()"}, false},
+		{map[string]string{}, false},
+	}
+
+	for _, test := range tests {
+		if matched := matchesSelector(test.selector, labels); matched != test.expected {
+			t.Fatalf("Selector %v: expected %v, got %v", test.selector, test.expected, matched)
+		}
+	}
+}
+
+func TestValidateSelector(t *testing.T) {
+	valid := []map[string]string{
+		{"tier": "web"},
+		{"tier": ""},
+		{"tier": "in (web"},
+		{"tier": "expr:in (web,api)"},
+		{"tier": "expr:!exists"},
+		{"tier": "expr:="},
+	}
+	for _, selector := range valid {
+		if err := validateSelector("serviceSelector", selector); err != nil {
+			t.Fatalf("Selector %v: unexpected %v", selector, err)
+		}
+	}
+
+	invalid := []map[string]string{
+		nil,
+		{"tier": "expr:notin ( , )"},
+		{"tier": "expr:web"},
+		{"tier": "expr:"},
+		{"": "web"},
+	}
+	for _, selector := range invalid {
+		if err := validateSelector("serviceSelector", selector); err == nil {
+			t.Fatalf("Expected selector %v to be invalid", selector)
+		}
+	}
+}
+
+func TestUpgradeLaunchConfigsSelector(t *testing.T) {
+	service := client.Service{
+		LaunchConfig: &client.LaunchConfig{
+			ImageUuid: "docker:rancher/web:1.0",
+			Labels:    map[string]interface{}{"tier": "web"},
+		},
+		SecondaryLaunchConfigs: []client.SecondaryLaunchConfig{
+			{Name: "sidekick", ImageUuid: "docker:rancher/web:1.0", Labels: map[string]interface{}{"tier": "web", "role": "sidekick"}},
+			{Name: "logger", ImageUuid: "docker:rancher/logger:1.0", Labels: map[string]interface{}{"tier": "logging"}},
+		},
+	}
+
+	tests := []struct {
+		selector    map[string]string
+		primary     bool
+		secondaries []string
+	}{
+		{map[string]string{"tier": "web"}, true, []string{"sidekick"}},
+		{map[string]string{"tier": "web", "role": "expr:!exists"}, true, nil},
+		{map[string]string{"role": "expr:exists"}, false, []string{"sidekick"}},
+		{map[string]string{"tier": "expr:in (web,logging)"}, true, []string{"sidekick", "logger"}},
+		{map[string]string{"tier": "expr:!=web"}, false, []string{"logger"}},
+		{map[string]string{"tier": "db"}, false, nil},
+	}
+
+	for _, test := range tests {
+		primary, secondaries, matched := upgradeLaunchConfigs(test.selector, service, "rancher/web:2.0")
+		if (primary != nil) != test.primary || len(secondaries) != len(test.secondaries) || matched != (test.primary || len(test.secondaries) > 0) {
+			t.Fatalf("Selector %v: unexpected primary %v, secondaries %v", test.selector, primary, secondaries)
+		}
+		if primary != nil && (primary.ImageUuid != "docker:rancher/web:2.0" || primary.Labels["io.rancher.container.pull_image"] != "always") {
+			t.Fatalf("Selector %v: primary not upgraded %#v", test.selector, primary)
+		}
+		for i, name := range test.secondaries {
+			if secondaries[i].Name != name || secondaries[i].ImageUuid != "docker:rancher/web:2.0" {
+				t.Fatalf("Selector %v: expected %s upgraded, got %#v", test.selector, name, secondaries[i])
+			}
+		}
+	}
+	if service.LaunchConfig.ImageUuid != "docker:rancher/web:1.0" || service.SecondaryLaunchConfigs[0].Labels["io.rancher.container.pull_image"] != nil {
+		t.Fatal("Upgrading launch configs should not modify the service")
+	}
+}
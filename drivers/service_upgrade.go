@@ -1,12 +1,14 @@
 package drivers
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -19,6 +21,17 @@ import (
 
 var regTag = regexp.MustCompile(`^[\w]+[\w.-]*`)
 
+const (
+	DefaultUpgradeTimeoutSeconds = 180
+	DefaultPollIntervalSeconds   = 5
+
+	upgradeOrderLabel = "io.rancher.webhook.upgrade.order"
+
+	AutoFinishFinish   = "finish"
+	AutoFinishManual   = "manual"
+	AutoFinishRollback = "rollback"
+)
+
 type ServiceUpgradeDriver struct {
 }
 
@@ -32,15 +45,24 @@ func (s *ServiceUpgradeDriver) ValidatePayload(conf interface{}, apiClient *clie
 		return http.StatusBadRequest, fmt.Errorf("Service selectors not provided")
 	}
 
+	if err := validateSelector("serviceSelector", config.ServiceSelector); err != nil {
+		return http.StatusBadRequest, err
+	}
+
 	if config.Tag == "" {
 		return http.StatusBadRequest, fmt.Errorf("Tag not provided")
 	}
 
-	err := IsValidTag(config.Tag)
-	if err != nil {
+	if _, err := newTagMatcher(config.TagMatch, config.Tag); err != nil {
 		return http.StatusBadRequest, err
 	}
 
+	for _, pattern := range config.ImageRepositories {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return http.StatusBadRequest, fmt.Errorf("Invalid image repository pattern %q", pattern)
+		}
+	}
+
 	if config.BatchSize <= 0 {
 		return http.StatusBadRequest, fmt.Errorf("Batch size for upgrade not provided/invalid")
 	}
@@ -49,22 +71,33 @@ func (s *ServiceUpgradeDriver) ValidatePayload(conf interface{}, apiClient *clie
 		return http.StatusBadRequest, fmt.Errorf("Batch interval for upgrade not provided/invalid")
 	}
 
+	if config.UpgradeTimeoutSeconds < 0 || config.PollIntervalSeconds < 0 {
+		return http.StatusBadRequest, fmt.Errorf("upgradeTimeoutSeconds and pollIntervalSeconds can't be negative")
+	}
+
+	timeoutSeconds := config.UpgradeTimeoutSeconds
+	if timeoutSeconds == 0 {
+		timeoutSeconds = DefaultUpgradeTimeoutSeconds
+	}
+	if config.PollIntervalSeconds > timeoutSeconds {
+		return http.StatusBadRequest, fmt.Errorf("pollIntervalSeconds %d can't be greater than upgradeTimeoutSeconds %d",
+			config.PollIntervalSeconds, timeoutSeconds)
+	}
+
+	switch config.AutoFinish {
+	case "", AutoFinishFinish, AutoFinishManual, AutoFinishRollback:
+	default:
+		return http.StatusBadRequest, fmt.Errorf("Invalid autoFinish %s, must be one of %s, %s or %s",
+			config.AutoFinish, AutoFinishFinish, AutoFinishManual, AutoFinishRollback)
+	}
+
 	return http.StatusOK, nil
 }
 
 func (s *ServiceUpgradeDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
-	var requestPayload interface{}
-	if request.Body != nil {
-		bytes, err := ioutil.ReadAll(request.Body)
-		if err != nil {
-			return http.StatusInternalServerError, fmt.Errorf("Error reading request body in Execute handler: %v", err)
-		}
-
-		if len(bytes) > 0 {
-			if err := json.Unmarshal(bytes, &requestPayload); err != nil {
-				return http.StatusInternalServerError, fmt.Errorf("Error unmarshalling request body in Execute handler: %v", err)
-			}
-		}
+	payload, err := readRequestBody(request)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Error reading request body in Execute handler: %v", err)
 	}
 
 	config := &model.ServiceUpgrade{}
@@ -72,155 +105,328 @@ func (s *ServiceUpgradeDriver) Execute(conf interface{}, apiClient *client.Ranch
 		return http.StatusInternalServerError, errors.Wrap(err, "Couldn't unmarshal config")
 	}
 
-	requestedTag := config.Tag
-	if requestPayload == nil {
-		return http.StatusBadRequest, fmt.Errorf("No Payload recevied from webhook")
-	}
-
-	requestBody, ok := requestPayload.(map[string]interface{})
-	if !ok {
-		return http.StatusBadRequest, fmt.Errorf("Body should be of type map[string]interface{}")
+	images, err := parsePushedImages(config.PayloadFormat, payload)
+	if err != nil {
+		return http.StatusBadRequest, err
 	}
 
-	pushedData, ok := requestBody["push_data"]
-	if !ok {
-		return http.StatusBadRequest, fmt.Errorf("Incomplete webhook response provided")
+	requestedTag := config.Tag
+	tagMatches, err := newTagMatcher(config.TagMatch, requestedTag)
+	if err != nil {
+		return http.StatusBadRequest, err
 	}
 
-	pushedTag, ok := pushedData.(map[string]interface{})["tag"].(string)
-	if !ok {
-		return http.StatusBadRequest, fmt.Errorf("Webhook response contains no tag")
+	job := JobFromRequest(request)
+	if len(images) == 0 {
+		job.SetMessage("Notification contains no pushed tags, skipping upgrade")
+		return http.StatusOK, nil
 	}
 
-	repository, ok := requestBody["repository"]
-	if !ok {
-		return http.StatusBadRequest, fmt.Errorf("Response provided without repository information")
-	}
-
-	pushedImage := ""
-	switch config.PayloadFormat {
-	case "alicloud":
-		alicloudFullName, fullnameOk := repository.(map[string]interface{})["repo_full_name"].(string)
-		alicloudRegion, regionOk := repository.(map[string]interface{})["region"].(string)
-		if fullnameOk && regionOk {
-			imageName := "registry." + alicloudRegion + ".aliyuncs.com/" + alicloudFullName
-			pushedImage = imageName + ":" + pushedTag
-		} else {
-			return http.StatusBadRequest, fmt.Errorf("Alicloud Docker Hub response provided without image name")
+	//a notification can carry several pushes, the last matching one is the most recent
+	var pushed *pushedImage
+	pushedTags := []string{}
+	for i := range images {
+		pushedTags = append(pushedTags, images[i].Tag)
+		if tagMatches(images[i].Tag) {
+			pushed = &images[i]
 		}
-	default:
-		imageName, ok := repository.(map[string]interface{})["repo_name"].(string)
-		if !ok {
-			return http.StatusBadRequest, fmt.Errorf("Response provided without image name")
-		}
-		pushedImage = imageName + ":" + pushedTag
 	}
-
-	if requestedTag != pushedTag {
+	if pushed == nil {
+		job.SetMessage(fmt.Sprintf("Pushed tag %s does not match tag %s, skipping upgrade", strings.Join(pushedTags, ", "), requestedTag))
 		return http.StatusOK, nil
 	}
+	if !allowedRepository(config.ImageRepositories, pushed.Repository) {
+		return http.StatusForbidden, fmt.Errorf("Image repository %s is not allowed by imageRepositories %v", pushed.Repository, config.ImageRepositories)
+	}
+	if config.PinDigest && pushed.Digest == "" {
+		job.SetMessage(fmt.Sprintf("Notification has no digest for %s, upgrading by tag", pushed))
+	}
 
-	log.Infof("Image %s pushed in Docker Hub, upgrading services with serviceSelector %v", pushedImage, config.ServiceSelector)
+	log.Infof("Image %s pushed, upgrading services with serviceSelector %v", pushed.describe(config.PinDigest), config.ServiceSelector)
 
-	go upgradeServices(apiClient, config, pushedImage)
+	job.Begin()
+	go upgradeServices(apiClient, config, *pushed, job)
 
 	return http.StatusOK, nil
 }
 
-func upgradeServices(apiClient *client.RancherClient, config *model.ServiceUpgrade, pushedImage string) {
-	var key, value string
-	var secondaryPresent, primaryPresent bool
-	serviceSelector := make(map[string]string)
-
-	for key, value = range config.ServiceSelector {
-		serviceSelector[key] = value
-	}
-	batchSize := config.BatchSize
-	intervalMillis := config.IntervalMillis
-	startFirst := config.StartFirst
+func upgradeServices(apiClient *client.RancherClient, config *model.ServiceUpgrade, pushed pushedImage, job *Job) {
+	defer job.End()
 	services, err := apiClient.Service.List(&client.ListOpts{})
 	if err != nil {
 		log.Errorf("Error %v in listing services", err)
+		job.Fail(fmt.Errorf("Error %v in listing services", err))
 		return
 	}
 
+	matched := []client.Service{}
 	for _, service := range services.Data {
-		secondaryPresent = false
-		primaryPresent = false
-		primaryLabels := service.LaunchConfig.Labels
-		secConfigs := []client.SecondaryLaunchConfig{}
-		for _, secLaunchConfig := range service.SecondaryLaunchConfigs {
-			labels := secLaunchConfig.Labels
-			for k, v := range labels {
-				if !strings.EqualFold(k, key) {
-					continue
-				}
-				if !strings.EqualFold(v.(string), value) {
-					continue
+		if _, _, ok := upgradeLaunchConfigs(config.ServiceSelector, service, ""); ok {
+			matched = append(matched, service)
+		}
+	}
+
+	if len(matched) == 0 {
+		job.SetMessage(fmt.Sprintf("No services matched serviceSelector %v", config.ServiceSelector))
+		return
+	}
+
+	waves := upgradeWaves(matched)
+	for _, wave := range waves {
+		for _, service := range wave {
+			job.SetResult(service.Id, ResultStateQueued, "Waiting for the service to be free to upgrade")
+		}
+	}
+
+	for i, wave := range waves {
+		if !upgradeWave(apiClient, config, wave, pushed, job) {
+			for _, remaining := range waves[i+1:] {
+				for _, service := range remaining {
+					job.SetResult(service.Id, ResultStateAborted, fmt.Sprintf("Aborted since upgrade wave %d failed or was superseded", i+1))
 				}
+			}
+			return
+		}
+	}
+}
 
-				secLaunchConfig.ImageUuid = "docker:" + pushedImage
-				secLaunchConfig.Labels["io.rancher.container.pull_image"] = "always"
-				secConfigs = append(secConfigs, secLaunchConfig)
-				secondaryPresent = true
+//upgradeWave upgrades the services of a wave in parallel, waiting for all of them and reporting whether every upgrade succeeded.
+//A service superseded by a newer upgrade doesn't complete the wave, the newer upgrade may still be running on it
+func upgradeWave(apiClient *client.RancherClient, config *model.ServiceUpgrade, wave []client.Service, pushed pushedImage, job *Job) bool {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := true
+
+	for _, service := range wave {
+		serviceID := service.Id
+		wg.Add(1)
+		job.Begin()
+		queued := upgrades.submit(serviceID, func() {
+			defer job.End()
+			defer wg.Done()
+			if !upgradeService(apiClient, config, serviceID, pushed, job) {
+				mu.Lock()
+				succeeded = false
+				mu.Unlock()
 			}
+		}, func() {
+			defer job.End()
+			defer wg.Done()
+			job.SetResult(serviceID, ResultStateSuperseded, "Superseded by a newer upgrade of the service")
+			mu.Lock()
+			succeeded = false
+			mu.Unlock()
+		})
+		if queued {
+			log.Infof("Upgrade of service %s queued behind the one running", serviceID)
 		}
+	}
+
+	wg.Wait()
+	return succeeded
+}
 
-		newLaunchConfig := service.LaunchConfig
-		for k, v := range primaryLabels {
-			if strings.EqualFold(k, key) {
-				if strings.EqualFold(v.(string), value) {
-					primaryPresent = true
-					newLaunchConfig.ImageUuid = "docker:" + pushedImage
-					newLaunchConfig.Labels["io.rancher.container.pull_image"] = "always"
+//upgradeWaves groups services by the upgrade order label of their primary launch config, lowest order first.
+//Services without the label, or with an invalid one, are upgraded in order 0
+func upgradeWaves(services []client.Service) [][]client.Service {
+	byOrder := map[int][]client.Service{}
+	for _, service := range services {
+		order := 0
+		if service.LaunchConfig != nil {
+			if label, ok := service.LaunchConfig.Labels[upgradeOrderLabel].(string); ok {
+				parsed, err := strconv.Atoi(strings.TrimSpace(label))
+				if err != nil {
+					log.Warnf("Invalid %s label %q on service %s, using order 0", upgradeOrderLabel, label, service.Id)
+				} else {
+					order = parsed
 				}
 			}
 		}
+		byOrder[order] = append(byOrder[order], service)
+	}
+
+	orders := []int{}
+	for order := range byOrder {
+		orders = append(orders, order)
+	}
+	sort.Ints(orders)
+
+	waves := [][]client.Service{}
+	for _, order := range orders {
+		waves = append(waves, byOrder[order])
+	}
+	return waves
+}
 
-		if !primaryPresent && !secondaryPresent {
+//upgradeLaunchConfigs returns copies of the primary and secondary launch configs of service matching selector,
+//pointed at image. matched is false when neither the primary nor any secondary launch config matches
+func upgradeLaunchConfigs(selector map[string]string, service client.Service, image string) (*client.LaunchConfig, []client.SecondaryLaunchConfig, bool) {
+	secConfigs := []client.SecondaryLaunchConfig{}
+	for _, secLaunchConfig := range service.SecondaryLaunchConfigs {
+		if !matchesSelector(selector, secLaunchConfig.Labels) {
 			continue
 		}
+		labels := map[string]interface{}{}
+		for k, v := range secLaunchConfig.Labels {
+			labels[k] = v
+		}
+		labels["io.rancher.container.pull_image"] = "always"
+		secLaunchConfig.Labels = labels
+		secLaunchConfig.ImageUuid = "docker:" + image
+		secConfigs = append(secConfigs, secLaunchConfig)
+	}
 
-		go func(service client.Service, apiClient *client.RancherClient, newLaunchConfig *client.LaunchConfig,
-			secConfigs []client.SecondaryLaunchConfig, primaryPresent bool, secondaryPresent bool) {
-			upgStrategy := &client.InServiceUpgradeStrategy{
-				BatchSize:      batchSize,
-				IntervalMillis: intervalMillis * 1000,
-				StartFirst:     startFirst,
-			}
-			if primaryPresent && secondaryPresent {
-				upgStrategy.LaunchConfig = newLaunchConfig
-				upgStrategy.SecondaryLaunchConfigs = secConfigs
-			} else if primaryPresent && !secondaryPresent {
-				upgStrategy.LaunchConfig = newLaunchConfig
-			} else if !primaryPresent && secondaryPresent {
-				upgStrategy.SecondaryLaunchConfigs = secConfigs
-			}
+	var newLaunchConfig *client.LaunchConfig
+	if service.LaunchConfig != nil && matchesSelector(selector, service.LaunchConfig.Labels) {
+		launchConfig := *service.LaunchConfig
+		labels := map[string]interface{}{}
+		for k, v := range launchConfig.Labels {
+			labels[k] = v
+		}
+		labels["io.rancher.container.pull_image"] = "always"
+		launchConfig.Labels = labels
+		launchConfig.ImageUuid = "docker:" + image
+		newLaunchConfig = &launchConfig
+	}
 
-			upgradedService, err := apiClient.Service.ActionUpgrade(&service, &client.ServiceUpgrade{
-				InServiceStrategy: upgStrategy,
-			})
-			if err != nil {
-				log.Errorf("Error %v in upgrading service %s", err, service.Id)
-				return
-			}
+	return newLaunchConfig, secConfigs, newLaunchConfig != nil || len(secConfigs) > 0
+}
 
-			if err := wait(apiClient, upgradedService); err != nil {
-				log.Errorln(err)
-				return
-			}
+//upgradeService upgrades a single service once the coordinator lets it run and reports whether it succeeded.
+//The service is reloaded since an earlier upgrade may have changed it while this one was queued
+func upgradeService(apiClient *client.RancherClient, config *model.ServiceUpgrade, serviceID string, pushed pushedImage, job *Job) bool {
+	pushedImage := pushed.reference(config.PinDigest)
+	description := pushed.describe(config.PinDigest)
 
-			if upgradedService.State != "upgraded" {
-				return
-			}
+	service, err := apiClient.Service.ById(serviceID)
+	if err != nil {
+		log.Errorf("Error %v in getting service %s", err, serviceID)
+		job.SetResult(serviceID, ResultStateError, fmt.Sprintf("Error %v in getting service", err))
+		return false
+	}
+	if service == nil || service.Removed != "" {
+		job.SetResult(serviceID, ResultStateError, "Service no longer exists")
+		return false
+	}
 
-			_, err = apiClient.Service.ActionFinishupgrade(upgradedService)
-			if err != nil {
-				log.Errorf("Error %v in finishUpgrade of service %s", err, upgradedService.Id)
-				return
-			}
-		}(service, apiClient, newLaunchConfig, secConfigs, primaryPresent, secondaryPresent)
+	newLaunchConfig, secConfigs, matched := upgradeLaunchConfigs(config.ServiceSelector, *service, pushedImage)
+	if !matched {
+		job.SetResult(serviceID, ResultStateSkipped, fmt.Sprintf("Service no longer matches serviceSelector %v", config.ServiceSelector))
+		return true
+	}
+
+	job.SetResult(serviceID, ResultStateUpgrading, "Upgrading to "+description)
+	upgStrategy := &client.InServiceUpgradeStrategy{
+		BatchSize:      config.BatchSize,
+		IntervalMillis: config.IntervalMillis * 1000,
+		StartFirst:     config.StartFirst,
+	}
+	if newLaunchConfig != nil {
+		upgStrategy.LaunchConfig = newLaunchConfig
+	}
+	if len(secConfigs) > 0 {
+		upgStrategy.SecondaryLaunchConfigs = secConfigs
+	}
+
+	upgradedService, err := apiClient.Service.ActionUpgrade(service, &client.ServiceUpgrade{
+		InServiceStrategy: upgStrategy,
+	})
+	if err != nil {
+		log.Errorf("Error %v in upgrading service %s", err, serviceID)
+		job.SetResult(serviceID, ResultStateError, fmt.Sprintf("Error %v in upgrading service", err))
+		return false
+	}
+
+	if err := waitFor(apiClient, upgradedService, config.UpgradeTimeoutSeconds, config.PollIntervalSeconds); err != nil {
+		log.Errorln(err)
+		failUpgrade(apiClient, upgradedService, config, err.Error(), job)
+		return false
+	}
+
+	if upgradedService.State != "upgraded" {
+		failUpgrade(apiClient, upgradedService, config, fmt.Sprintf("Upgrade ended in state %s", upgradedService.State), job)
+		return false
+	}
+
+	switch config.AutoFinish {
+	case AutoFinishManual:
+		job.SetResult(serviceID, ResultStateUpgraded, "Upgraded to "+description+", waiting for the upgrade to be finished manually")
+		return true
+	case AutoFinishRollback:
+		if err := rollback(apiClient, upgradedService, config.UpgradeTimeoutSeconds, config.PollIntervalSeconds); err != nil {
+			log.Errorln(err)
+			job.SetResult(serviceID, ResultStateError, fmt.Sprintf("Upgraded to %s, rollback failed: %v", description, err))
+			return false
+		}
+		job.SetResult(serviceID, ResultStateRolledBack, "Upgraded to "+description+" and rolled back")
+		return true
+	}
+
+	_, err = apiClient.Service.ActionFinishupgrade(upgradedService)
+	if err != nil {
+		log.Errorf("Error %v in finishUpgrade of service %s", err, upgradedService.Id)
+		job.SetResult(serviceID, ResultStateError, fmt.Sprintf("Error %v in finishUpgrade of service", err))
+		return false
 	}
+	job.SetResult(serviceID, ResultStateUpgraded, "Upgraded to "+description)
+	return true
+}
+
+//allowedRepository reports whether repository matches one of the glob patterns, an empty list allows any repository
+func allowedRepository(patterns []string, repository string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, repository); matched {
+			return true
+		}
+	}
+	return false
+}
+
+//failUpgrade records a failed upgrade, rolling the service back to its previous launch config when autoRollback is set
+func failUpgrade(apiClient *client.RancherClient, service *client.Service, config *model.ServiceUpgrade, reason string, job *Job) {
+	if !config.AutoRollback {
+		job.SetResult(service.Id, ResultStateError, reason)
+		return
+	}
+
+	log.Infof("Rolling back service %s: %s", service.Id, reason)
+	if err := rollback(apiClient, service, config.UpgradeTimeoutSeconds, config.PollIntervalSeconds); err != nil {
+		log.Errorln(err)
+		job.SetResult(service.Id, ResultStateError, fmt.Sprintf("%s, rollback failed: %v", reason, err))
+		return
+	}
+	job.SetResult(service.Id, ResultStateError, fmt.Sprintf("%s, rolled back", reason))
+}
+
+//rollback reverts service to the launch config it had before its upgrade and waits up to timeoutSeconds for it to settle.
+//Rancher only rolls back upgraded services or canceled upgrades, an upgrade still running is canceled first
+func rollback(apiClient *client.RancherClient, service *client.Service, timeoutSeconds int64, pollIntervalSeconds int64) error {
+	current := *service
+	if err := apiClient.Reload(&current.Resource, &current); err != nil {
+		return fmt.Errorf("Error %v in getting service %s", err, service.Id)
+	}
+	if current.State == "upgrading" {
+		canceledService, err := apiClient.Service.ActionCancelupgrade(&current)
+		if err != nil {
+			return fmt.Errorf("Error %v in canceling upgrade of service %s", err, service.Id)
+		}
+		if err := waitFor(apiClient, canceledService, timeoutSeconds, pollIntervalSeconds); err != nil {
+			return err
+		}
+		if canceledService.State != "canceled-upgrade" {
+			return fmt.Errorf("Canceling upgrade of service %s ended in state %s", service.Id, canceledService.State)
+		}
+		current = *canceledService
+	}
+
+	rolledBackService, err := apiClient.Service.ActionRollback(&current)
+	if err != nil {
+		return fmt.Errorf("Error %v in rolling back service %s", err, service.Id)
+	}
+	return waitFor(apiClient, rolledBackService, timeoutSeconds, pollIntervalSeconds)
 }
 
 func (s *ServiceUpgradeDriver) ConvertToConfigAndSetOnWebhook(conf interface{}, webhook *model.Webhook) error {
@@ -246,15 +452,26 @@ func (s *ServiceUpgradeDriver) GetDriverConfigResource() interface{} {
 }
 
 func (s *ServiceUpgradeDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Schema {
-	options := []string{"dockerhub", "alicloud"}
+	options := []string{PayloadFormatDockerhub, PayloadFormatAlicloud, PayloadFormatRegistryV2, PayloadFormatHarbor, PayloadFormatQuay,
+		PayloadFormatGithub}
 	minValue := int64(1)
 
+	describeSelector(schema)
+
 	payloadFormat := schema.ResourceFields["payloadFormat"]
 	payloadFormat.Type = "enum"
 	payloadFormat.Options = options
 	payloadFormat.Default = options[0]
+	payloadFormat.Description = "Format of the registry notification, registryV2 also covers the GitLab container registry"
 	schema.ResourceFields["payloadFormat"] = payloadFormat
 
+	tagMatch := schema.ResourceFields["tagMatch"]
+	tagMatch.Type = "enum"
+	tagMatch.Options = []string{TagMatchExact, TagMatchRegex, TagMatchGlob, TagMatchSemver}
+	tagMatch.Default = TagMatchExact
+	tagMatch.Description = "How tag is matched, semver constraints such as ~1.4 only match prerelease tags when they name a prerelease themselves"
+	schema.ResourceFields["tagMatch"] = tagMatch
+
 	batchSize := schema.ResourceFields["batchSize"]
 	batchSize.Default = 1
 	batchSize.Min = &minValue
@@ -263,24 +480,57 @@ func (s *ServiceUpgradeDriver) CustomizeSchema(schema *v1client.Schema) *v1clien
 	intervalMillis := schema.ResourceFields["intervalMillis"]
 	intervalMillis.Default = 2
 	intervalMillis.Min = &minValue
+	intervalMillis.Description = "Seconds between batches"
 	schema.ResourceFields["intervalMillis"] = intervalMillis
 
 	startFirst := schema.ResourceFields["startFirst"]
 	startFirst.Default = false
 	schema.ResourceFields["startFirst"] = startFirst
 
+	pinDigest := schema.ResourceFields["pinDigest"]
+	pinDigest.Default = false
+	schema.ResourceFields["pinDigest"] = pinDigest
+
+	autoRollback := schema.ResourceFields["autoRollback"]
+	autoRollback.Default = false
+	schema.ResourceFields["autoRollback"] = autoRollback
+
+	upgradeTimeoutSeconds := schema.ResourceFields["upgradeTimeoutSeconds"]
+	upgradeTimeoutSeconds.Default = DefaultUpgradeTimeoutSeconds
+	upgradeTimeoutSeconds.Min = &minValue
+	schema.ResourceFields["upgradeTimeoutSeconds"] = upgradeTimeoutSeconds
+
+	pollIntervalSeconds := schema.ResourceFields["pollIntervalSeconds"]
+	pollIntervalSeconds.Default = DefaultPollIntervalSeconds
+	pollIntervalSeconds.Min = &minValue
+	schema.ResourceFields["pollIntervalSeconds"] = pollIntervalSeconds
+
+	autoFinish := schema.ResourceFields["autoFinish"]
+	autoFinish.Type = "enum"
+	autoFinish.Options = []string{AutoFinishFinish, AutoFinishManual, AutoFinishRollback}
+	autoFinish.Default = AutoFinishFinish
+	schema.ResourceFields["autoFinish"] = autoFinish
+
 	return schema
 }
 
-func wait(apiClient *client.RancherClient, service *client.Service) error {
-	for i := 0; i < 36; i++ {
+//waitFor polls service every pollIntervalSeconds until it stops transitioning or timeoutSeconds have passed
+func waitFor(apiClient *client.RancherClient, service *client.Service, timeoutSeconds int64, pollIntervalSeconds int64) error {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = DefaultUpgradeTimeoutSeconds
+	}
+	if pollIntervalSeconds <= 0 {
+		pollIntervalSeconds = DefaultPollIntervalSeconds
+	}
+	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
+	for {
 		if err := apiClient.Reload(&service.Resource, service); err != nil {
 			return err
 		}
-		if service.Transitioning != "yes" {
+		if service.Transitioning != "yes" || !time.Now().Before(deadline) {
 			break
 		}
-		time.Sleep(5 * time.Second)
+		time.Sleep(time.Duration(pollIntervalSeconds) * time.Second)
 	}
 
 	switch service.Transitioning {
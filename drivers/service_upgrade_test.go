@@ -0,0 +1,335 @@
+package drivers
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestAllowedRepository(t *testing.T) {
+	tests := []struct {
+		patterns   []string
+		repository string
+		expected   bool
+	}{
+		{nil, "rancher/webhook-service", true},
+		{[]string{"rancher/webhook-service"}, "rancher/webhook-service", true},
+		{[]string{"rancher/*"}, "rancher/webhook-service", true},
+		{[]string{"rancher/*"}, "evil/webhook-service", false},
+		{[]string{"registry.example.com:5000/*/*"}, "registry.example.com:5000/rancher/webhook-service", true},
+		{[]string{"registry.example.com:5000/*"}, "registry.example.com:5000/rancher/webhook-service", false},
+		{[]string{"quay.io/rancher/*", "ghcr.io/rancher/*"}, "ghcr.io/rancher/webhook-service", true},
+	}
+
+	for _, test := range tests {
+		if allowed := allowedRepository(test.patterns, test.repository); allowed != test.expected {
+			t.Fatalf("Patterns %v with repository %s: expected %v, got %v", test.patterns, test.repository, test.expected, allowed)
+		}
+	}
+}
+
+func TestServiceUpgradeImageRepositories(t *testing.T) {
+	driver := &ServiceUpgradeDriver{}
+	config := model.ServiceUpgrade{
+		ServiceSelector:   map[string]string{"foo": "bar"},
+		Tag:               "1.2.0",
+		ImageRepositories: []string{"rancher/*"},
+		BatchSize:         1,
+		IntervalMillis:    2,
+	}
+
+	if code, err := driver.ValidatePayload(config, nil); err != nil {
+		t.Fatalf("Expected valid config, got %d %v", code, err)
+	}
+
+	request, err := http.NewRequest("POST", "/", bytes.NewBufferString(
+		`{"push_data": {"tag": "1.2.0"}, "repository": {"repo_name": "evil/webhook-service"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := driver.Execute(config, nil, request)
+	if code != http.StatusForbidden || err == nil {
+		t.Fatalf("Expected forbidden for repository outside imageRepositories, got %d %v", code, err)
+	}
+
+	config.ImageRepositories = []string{"rancher/["}
+	if code, err := driver.ValidatePayload(config, nil); code != http.StatusBadRequest || err == nil {
+		t.Fatalf("Expected bad request for invalid pattern, got %d %v", code, err)
+	}
+}
+
+func TestServiceUpgradeFinishValidation(t *testing.T) {
+	driver := &ServiceUpgradeDriver{}
+	valid := model.ServiceUpgrade{
+		ServiceSelector:       map[string]string{"foo": "bar"},
+		Tag:                   "1.2.0",
+		BatchSize:             1,
+		IntervalMillis:        2,
+		UpgradeTimeoutSeconds: 600,
+		PollIntervalSeconds:   10,
+		AutoFinish:            AutoFinishManual,
+	}
+	if code, err := driver.ValidatePayload(valid, nil); err != nil {
+		t.Fatalf("Expected valid config, got %d %v", code, err)
+	}
+
+	invalid := []func(config *model.ServiceUpgrade){
+		func(config *model.ServiceUpgrade) { config.UpgradeTimeoutSeconds = -1 },
+		func(config *model.ServiceUpgrade) { config.PollIntervalSeconds = -1 },
+		func(config *model.ServiceUpgrade) { config.PollIntervalSeconds = 601 },
+		//without upgradeTimeoutSeconds the poll interval is checked against the default timeout
+		func(config *model.ServiceUpgrade) {
+			config.UpgradeTimeoutSeconds = 0
+			config.PollIntervalSeconds = DefaultUpgradeTimeoutSeconds + 1
+		},
+		func(config *model.ServiceUpgrade) { config.AutoFinish = "later" },
+	}
+	for i, modify := range invalid {
+		config := valid
+		modify(&config)
+		if code, err := driver.ValidatePayload(config, nil); code != http.StatusBadRequest || err == nil {
+			t.Fatalf("Case %d: expected bad request, got %d %v", i, code, err)
+		}
+	}
+}
+
+func TestUpgradeWaves(t *testing.T) {
+	service := func(id string, order string) client.Service {
+		labels := map[string]interface{}{"foo": "bar"}
+		if order != "" {
+			labels[upgradeOrderLabel] = order
+		}
+		return client.Service{
+			Resource:     client.Resource{Id: id},
+			LaunchConfig: &client.LaunchConfig{Labels: labels},
+		}
+	}
+
+	waves := upgradeWaves([]client.Service{
+		service("cron", "3"),
+		service("api", "1"),
+		service("worker", "2"),
+		service("web", "1"),
+		service("sidekick", ""),
+		service("broken", "first"),
+		service("db", "-1"),
+	})
+
+	ids := [][]string{}
+	for _, wave := range waves {
+		waveIDs := []string{}
+		for _, service := range wave {
+			waveIDs = append(waveIDs, service.Id)
+		}
+		ids = append(ids, waveIDs)
+	}
+
+	expected := [][]string{{"db"}, {"sidekick", "broken"}, {"api", "web"}, {"worker"}, {"cron"}}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Expected waves %v, got %v", expected, ids)
+	}
+}
+
+func TestFailUpgradeRollbackTimeout(t *testing.T) {
+	apiClient, _ := newFakeClient(client.Service{Resource: client.Resource{Id: "1s1"}, State: "upgraded"})
+	apiClient.RancherBaseClient.(*fakeBaseClient).transitioning["1s1"] = true
+	config := &model.ServiceUpgrade{AutoRollback: true, UpgradeTimeoutSeconds: 1, PollIntervalSeconds: 1}
+	job := NewJob("1j1", "1a1", "1wr-upgrade", "serviceUpgrade")
+
+	started := time.Now()
+	failUpgrade(apiClient, &client.Service{Resource: client.Resource{Id: "1s1"}}, config, "Upgrade ended in state active", job)
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("Rollback should give up after upgradeTimeoutSeconds, took %v", elapsed)
+	}
+	state, message := result(job, "1s1")
+	if state != ResultStateError || !strings.Contains(message, "rollback failed: Timeout") {
+		t.Fatalf("Unexpected result %s %q", state, message)
+	}
+}
+
+func TestUpgradeTimeoutCancelsBeforeRollback(t *testing.T) {
+	apiClient, services := newFakeClient(client.Service{
+		Resource: client.Resource{Id: "1s1"},
+		LaunchConfig: &client.LaunchConfig{
+			ImageUuid: "docker:rancher/webhook-service:1.1.0",
+			Labels:    map[string]interface{}{"foo": "bar"},
+		},
+	})
+	services.stuck["1s1"] = true
+	config := &model.ServiceUpgrade{ServiceSelector: map[string]string{"foo": "bar"}, BatchSize: 1, IntervalMillis: 1,
+		AutoRollback: true, UpgradeTimeoutSeconds: 1, PollIntervalSeconds: 1}
+
+	defer func(coordinator *upgradeCoordinator) { upgrades = coordinator }(upgrades)
+	upgrades = newUpgradeCoordinator(0)
+
+	job := NewJob("1j1", "1a1", "1wr-upgrade", "serviceUpgrade")
+	job.Begin()
+	upgradeServices(apiClient, config, pushedImage{Repository: "rancher/webhook-service", Tag: "1.2.0"}, job)
+
+	//a timed out upgrade is still upgrading, Rancher only rolls it back once it's canceled
+	if actions := services.actions; !reflect.DeepEqual(actions, []string{"upgrade 1s1", "cancelupgrade 1s1", "rollback 1s1"}) {
+		t.Fatalf("Expected the upgrade to be canceled before rolling back, got %v", actions)
+	}
+	state, message := result(job, "1s1")
+	if state != ResultStateError || !strings.Contains(message, "Timeout") || !strings.HasSuffix(message, "rolled back") {
+		t.Fatalf("Unexpected result %s %q", state, message)
+	}
+	if service, _ := services.ById("1s1"); service.State != "active" {
+		t.Fatalf("Expected rolled back service to be active, got %s", service.State)
+	}
+}
+
+func TestUpgradeAutoFinish(t *testing.T) {
+	defer func(coordinator *upgradeCoordinator) { upgrades = coordinator }(upgrades)
+	upgrades = newUpgradeCoordinator(0)
+
+	tests := []struct {
+		autoFinish string
+		actions    []string
+		state      string
+		message    string
+	}{
+		{"", []string{"upgrade 1s1", "finishupgrade 1s1"}, ResultStateUpgraded, "Upgraded to rancher/webhook-service:1.2.0"},
+		{AutoFinishFinish, []string{"upgrade 1s1", "finishupgrade 1s1"}, ResultStateUpgraded, "Upgraded to rancher/webhook-service:1.2.0"},
+		{AutoFinishManual, []string{"upgrade 1s1"}, ResultStateUpgraded, "waiting for the upgrade to be finished manually"},
+		{AutoFinishRollback, []string{"upgrade 1s1", "rollback 1s1"}, ResultStateRolledBack, "and rolled back"},
+	}
+	for _, test := range tests {
+		apiClient, services := newFakeClient(client.Service{
+			Resource: client.Resource{Id: "1s1"},
+			LaunchConfig: &client.LaunchConfig{
+				ImageUuid: "docker:rancher/webhook-service:1.1.0",
+				Labels:    map[string]interface{}{"foo": "bar"},
+			},
+		})
+		config := &model.ServiceUpgrade{ServiceSelector: map[string]string{"foo": "bar"}, BatchSize: 1, IntervalMillis: 1,
+			AutoFinish: test.autoFinish}
+		job := NewJob("1j1", "1a1", "1wr-upgrade", "serviceUpgrade")
+		job.Begin()
+		upgradeServices(apiClient, config, pushedImage{Repository: "rancher/webhook-service", Tag: "1.2.0"}, job)
+
+		if !reflect.DeepEqual(services.actions, test.actions) {
+			t.Fatalf("autoFinish %q: expected actions %v, got %v", test.autoFinish, test.actions, services.actions)
+		}
+		if state, message := result(job, "1s1"); state != test.state || !strings.Contains(message, test.message) {
+			t.Fatalf("autoFinish %q: unexpected result %s %q", test.autoFinish, state, message)
+		}
+	}
+}
+
+func TestUpgradeWaitTimeout(t *testing.T) {
+	apiClient, services := newFakeClient(client.Service{
+		Resource: client.Resource{Id: "1s1"},
+		LaunchConfig: &client.LaunchConfig{
+			ImageUuid: "docker:rancher/webhook-service:1.1.0",
+			Labels:    map[string]interface{}{"foo": "bar"},
+		},
+	})
+	services.stuck["1s1"] = true
+	config := &model.ServiceUpgrade{ServiceSelector: map[string]string{"foo": "bar"}, BatchSize: 1, IntervalMillis: 1,
+		UpgradeTimeoutSeconds: 2, PollIntervalSeconds: 1}
+
+	defer func(coordinator *upgradeCoordinator) { upgrades = coordinator }(upgrades)
+	upgrades = newUpgradeCoordinator(0)
+
+	job := NewJob("1j1", "1a1", "1wr-upgrade", "serviceUpgrade")
+	job.Begin()
+	started := time.Now()
+	upgradeServices(apiClient, config, pushedImage{Repository: "rancher/webhook-service", Tag: "1.2.0"}, job)
+
+	//the wait polls until upgradeTimeoutSeconds instead of the default timeout
+	if elapsed := time.Since(started); elapsed < time.Second || elapsed > 5*time.Second {
+		t.Fatalf("Upgrade should time out after upgradeTimeoutSeconds, took %v", elapsed)
+	}
+	if !reflect.DeepEqual(services.actions, []string{"upgrade 1s1"}) {
+		t.Fatalf("Expected only the upgrade without autoRollback, got %v", services.actions)
+	}
+	if state, message := result(job, "1s1"); state != ResultStateError || !strings.Contains(message, "Timeout") {
+		t.Fatalf("Unexpected result %s %q", state, message)
+	}
+}
+
+func TestUpgradeServicesAbortsLaterWaves(t *testing.T) {
+	ordered := func(id string, order string) client.Service {
+		return client.Service{
+			Resource: client.Resource{Id: id},
+			LaunchConfig: &client.LaunchConfig{
+				ImageUuid: "docker:rancher/webhook-service:1.1.0",
+				Labels:    map[string]interface{}{"foo": "bar", upgradeOrderLabel: order},
+			},
+		}
+	}
+	config := &model.ServiceUpgrade{ServiceSelector: map[string]string{"foo": "bar"}, BatchSize: 1, IntervalMillis: 1}
+	pushed := pushedImage{Repository: "rancher/webhook-service", Tag: "1.2.0"}
+
+	defer func(coordinator *upgradeCoordinator) { upgrades = coordinator }(upgrades)
+	upgrades = newUpgradeCoordinator(0)
+
+	//a failed upgrade in the first wave aborts the second
+	apiClient, services := newFakeClient(ordered("1s1", "1"), ordered("1s2", "1"), ordered("1s3", "2"))
+	services.failing["1s2"] = true
+	job := NewJob("1j1", "1a1", "1wr-upgrade", "serviceUpgrade")
+	job.Begin()
+	upgradeServices(apiClient, config, pushed, job)
+
+	if actions := services.recordedActions(); !reflect.DeepEqual(actions, []string{"finishupgrade 1s1", "upgrade 1s1", "upgrade 1s2"}) {
+		t.Fatalf("Second wave should not upgrade, got %v", actions)
+	}
+	expected := map[string]string{"1s1": ResultStateUpgraded, "1s2": ResultStateError, "1s3": ResultStateAborted}
+	for id, state := range expected {
+		if actual, message := result(job, id); actual != state {
+			t.Fatalf("Service %s: expected result %q, got %q %q", id, state, actual, message)
+		}
+	}
+
+	//a service superseded by a newer upgrade while queued doesn't complete its wave either
+	apiClient, services = newFakeClient(ordered("1s1", "1"), ordered("1s3", "2"))
+	release := make(chan struct{})
+	upgrades.submit("1s1", func() { <-release }, func() {})
+
+	job = NewJob("1j2", "1a1", "1wr-upgrade", "serviceUpgrade")
+	job.Begin()
+	done := make(chan struct{})
+	go func() {
+		upgradeServices(apiClient, config, pushed, job)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		upgrades.Lock()
+		queued := upgrades.pending["1s1"] != nil
+		upgrades.Unlock()
+		if queued {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for the upgrade to queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	upgrades.submit("1s1", func() {}, func() {})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the superseded upgrade")
+	}
+	close(release)
+
+	if actions := services.recordedActions(); len(actions) != 0 {
+		t.Fatalf("No service should be upgraded by the superseded job, got %v", actions)
+	}
+	expected = map[string]string{"1s1": ResultStateSuperseded, "1s3": ResultStateAborted}
+	for id, state := range expected {
+		if actual, message := result(job, id); actual != state {
+			t.Fatalf("Service %s: expected result %q, got %q %q", id, state, actual, message)
+		}
+	}
+}
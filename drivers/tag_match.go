@@ -0,0 +1,165 @@
+package drivers
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+const (
+	TagMatchExact  = "exact"
+	TagMatchRegex  = "regex"
+	TagMatchGlob   = "glob"
+	TagMatchSemver = "semver"
+)
+
+var regComparator = regexp.MustCompile(`(~|\^|>=|<=|!=|==|>|<|=|!)?\s*v?([0-9]+(?:\.[0-9xX*]+){0,2}(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)`)
+
+//newTagMatcher returns a function reporting whether a pushed tag matches pattern under the given tagMatch mode
+func newTagMatcher(mode string, pattern string) (func(string) bool, error) {
+	switch mode {
+	case "", TagMatchExact:
+		if err := IsValidTag(pattern); err != nil {
+			return nil, err
+		}
+		return func(tag string) bool {
+			return tag == pattern
+		}, nil
+	case TagMatchRegex:
+		reg, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("Invalid tag regex %s: %v", pattern, err)
+		}
+		return reg.MatchString, nil
+	case TagMatchGlob:
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid tag glob %s: %v", pattern, err)
+		}
+		return func(tag string) bool {
+			matched, _ := path.Match(pattern, tag)
+			return matched
+		}, nil
+	case TagMatchSemver:
+		versionRange, err := parseSemverRange(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid semver constraint %s: %v", pattern, err)
+		}
+		return func(tag string) bool {
+			version, err := semver.ParseTolerant(tag)
+			if err != nil {
+				return false
+			}
+			return versionRange(version)
+		}, nil
+	}
+	return nil, fmt.Errorf("Invalid tagMatch %s, must be one of %s, %s, %s or %s", mode, TagMatchExact, TagMatchRegex, TagMatchGlob, TagMatchSemver)
+}
+
+//parseSemverRange parses constraints such as "~1.4", "^2.1" or ">=2.0.0 <3", expanding tilde and caret
+//and padding partial versions before handing the result to semver.ParseRange
+func parseSemverRange(constraint string) (semver.Range, error) {
+	ranges := []semver.Range{}
+	for _, part := range strings.Split(constraint, "||") {
+		if strings.TrimSpace(part) == "" {
+			return nil, fmt.Errorf("Empty constraint")
+		}
+		if strings.TrimSpace(regComparator.ReplaceAllString(part, "")) != "" {
+			return nil, fmt.Errorf("Unexpected characters in constraint %q", strings.TrimSpace(part))
+		}
+
+		andParts := []string{}
+		prereleases := []semver.Version{}
+		for _, match := range regComparator.FindAllStringSubmatch(part, -1) {
+			comparators := expandComparator(match[1], match[2])
+			if version, err := semver.Parse(strings.TrimLeft(comparators[0], "<>=!")); err == nil && len(version.Pre) > 0 {
+				prereleases = append(prereleases, version)
+			}
+			andParts = append(andParts, comparators...)
+		}
+		versionRange, err := semver.ParseRange(strings.Join(andParts, " "))
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, excludePrereleases(versionRange, prereleases))
+	}
+
+	return func(version semver.Version) bool {
+		for _, versionRange := range ranges {
+			if versionRange(version) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+//excludePrereleases keeps prerelease tags such as 1.4.2-rc1 out of a range unless one of its comparators
+//names a prerelease of the same major.minor.patch, the way npm ranges behave
+func excludePrereleases(versionRange semver.Range, prereleases []semver.Version) semver.Range {
+	return func(version semver.Version) bool {
+		if !versionRange(version) {
+			return false
+		}
+		if len(version.Pre) == 0 {
+			return true
+		}
+		for _, prerelease := range prereleases {
+			if prerelease.Major == version.Major && prerelease.Minor == version.Minor && prerelease.Patch == version.Patch {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//expandComparator rewrites a single comparator into the forms understood by semver.ParseRange
+func expandComparator(op string, version string) []string {
+	if strings.ContainsAny(version, "xX*") {
+		if op == "" || op == "~" || op == "^" {
+			op = "="
+		}
+		return []string{op + version}
+	}
+
+	core := version
+	suffix := ""
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		core, suffix = version[:i], version[i:]
+	}
+	parts := strings.Split(core, ".")
+	nums := make([]uint64, 3)
+	for i, p := range parts {
+		nums[i], _ = strconv.ParseUint(p, 10, 64)
+	}
+	full := fmt.Sprintf("%d.%d.%d%s", nums[0], nums[1], nums[2], suffix)
+
+	switch op {
+	case "~":
+		if len(parts) == 1 {
+			return []string{">=" + full, fmt.Sprintf("<%d.0.0", nums[0]+1)}
+		}
+		return []string{">=" + full, fmt.Sprintf("<%d.%d.0", nums[0], nums[1]+1)}
+	case "^":
+		switch {
+		case nums[0] > 0 || len(parts) == 1:
+			return []string{">=" + full, fmt.Sprintf("<%d.0.0", nums[0]+1)}
+		case nums[1] > 0 || len(parts) == 2:
+			return []string{">=" + full, fmt.Sprintf("<0.%d.0", nums[1]+1)}
+		default:
+			return []string{">=" + full, fmt.Sprintf("<0.0.%d", nums[2]+1)}
+		}
+	case "":
+		//a bare partial version such as "1.4" means any patch of 1.4
+		if len(parts) < 3 {
+			return expandComparator("~", version)
+		}
+		return []string{"=" + full}
+	case "==":
+		return []string{"=" + full}
+	}
+	return []string{op + full}
+}
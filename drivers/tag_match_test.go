@@ -0,0 +1,72 @@
+package drivers
+
+import (
+	"testing"
+)
+
+func TestTagMatcher(t *testing.T) {
+	tests := []struct {
+		mode    string
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{"", "1.2.0", []string{"1.2.0"}, []string{"1.2.1", "v1.2.0", "1.2"}},
+		{TagMatchExact, "latest", []string{"latest"}, []string{"latest-rc", "Latest"}},
+		{TagMatchRegex, `v?1\.[0-9]+\.[0-9]+`, []string{"1.2.0", "v1.10.3"}, []string{"1.2.0-rc1", "21.2.0", "2.0.0"}},
+		{TagMatchRegex, "release|stable", []string{"release", "stable"}, []string{"prerelease", "stable-1"}},
+		{TagMatchGlob, "1.2.*", []string{"1.2.0", "1.2.0-rc1"}, []string{"1.3.0", "v1.2.0"}},
+		{TagMatchGlob, "build-?", []string{"build-1"}, []string{"build-10", "build-"}},
+		{TagMatchSemver, "~1.4", []string{"1.4.0", "1.4.2", "v1.4.9"}, []string{"1.4.2-rc1", "1.5.0", "1.3.9", "latest"}},
+		{TagMatchSemver, "~1.4.2", []string{"1.4.2", "1.4.7"}, []string{"1.4.1", "1.5.0"}},
+		{TagMatchSemver, "^0.2", []string{"0.2.0", "0.2.5"}, []string{"0.3.0", "0.1.9", "1.2.0", "0.2.1-beta"}},
+		{TagMatchSemver, "^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"2.0.0", "1.2.2"}},
+		{TagMatchSemver, ">=2.0.0 <3", []string{"2.0.0", "2.9.9", "v2.1.0"}, []string{"3.0.0", "1.9.9", "3.0.0-rc1", "2.5.0-rc1"}},
+		{TagMatchSemver, "1.x", []string{"1.0.0", "1.9.3"}, []string{"2.0.0", "0.9.0", "1.2.0-alpha"}},
+		{TagMatchSemver, "1.4", []string{"1.4.0", "1.4.3"}, []string{"1.5.0"}},
+		{TagMatchSemver, "<1.0.0 || >=2.0.0", []string{"0.9.0", "2.0.0"}, []string{"1.0.0", "1.5.0"}},
+		//a constraint naming a prerelease allows prereleases of that version only
+		{TagMatchSemver, ">=1.4.2-rc1 <1.5.0", []string{"1.4.2-rc1", "1.4.2-rc2", "1.4.2", "1.4.3"}, []string{"1.4.3-rc1", "1.4.2-alpha"}},
+		{TagMatchSemver, "~1.4.2-rc1", []string{"1.4.2-rc1", "1.4.2", "1.4.5"}, []string{"1.4.5-rc1", "1.5.0"}},
+	}
+
+	for _, test := range tests {
+		matches, err := newTagMatcher(test.mode, test.pattern)
+		if err != nil {
+			t.Fatalf("%s %s: unexpected %v", test.mode, test.pattern, err)
+		}
+		for _, tag := range test.matches {
+			if !matches(tag) {
+				t.Fatalf("%s %s should match %s", test.mode, test.pattern, tag)
+			}
+		}
+		for _, tag := range test.misses {
+			if matches(tag) {
+				t.Fatalf("%s %s should not match %s", test.mode, test.pattern, tag)
+			}
+		}
+	}
+}
+
+func TestTagMatcherInvalidPattern(t *testing.T) {
+	tests := []struct {
+		mode    string
+		pattern string
+	}{
+		{TagMatchExact, "-bad"},
+		{TagMatchExact, ""},
+		{TagMatchRegex, "1.(2"},
+		{TagMatchGlob, "1.[2"},
+		{TagMatchSemver, ""},
+		{TagMatchSemver, "latest"},
+		{TagMatchSemver, "~1.4 ||"},
+		{TagMatchSemver, ">=1.0.0 & <2"},
+		{"loose", "1.2.0"},
+	}
+
+	for _, test := range tests {
+		if _, err := newTagMatcher(test.mode, test.pattern); err == nil {
+			t.Fatalf("Expected %s %q to be rejected", test.mode, test.pattern)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package drivers
+
+import (
+	"sync"
+)
+
+//DefaultMaxConcurrentUpgrades is the number of services upgraded at the same time unless configured otherwise
+const DefaultMaxConcurrentUpgrades = 10
+
+//queuedUpgrade is an upgrade waiting for the one running on the same service to end
+type queuedUpgrade struct {
+	run        func()
+	superseded func()
+}
+
+//upgradeCoordinator runs at most one upgrade per service and caps the number of services upgrading at once.
+//An upgrade requested while the service is upgrading waits for it, replacing any upgrade already waiting
+type upgradeCoordinator struct {
+	sync.Mutex
+	slots   chan struct{}
+	pending map[string]*queuedUpgrade
+}
+
+var upgrades = newUpgradeCoordinator(DefaultMaxConcurrentUpgrades)
+
+func newUpgradeCoordinator(maxConcurrent int) *upgradeCoordinator {
+	coordinator := &upgradeCoordinator{pending: map[string]*queuedUpgrade{}}
+	if maxConcurrent > 0 {
+		coordinator.slots = make(chan struct{}, maxConcurrent)
+	}
+	return coordinator
+}
+
+//SetMaxConcurrentUpgrades caps the number of services upgraded at the same time, zero or less removes the cap
+func SetMaxConcurrentUpgrades(maxConcurrent int) {
+	upgrades = newUpgradeCoordinator(maxConcurrent)
+}
+
+//submit schedules run for serviceID and reports whether it had to queue behind a running upgrade.
+//superseded is called instead of run when a newer upgrade replaces this one before it starts
+func (c *upgradeCoordinator) submit(serviceID string, run func(), superseded func()) bool {
+	c.Lock()
+	queued, upgrading := c.pending[serviceID]
+	if !upgrading {
+		c.pending[serviceID] = nil
+		c.Unlock()
+		go c.start(serviceID, run)
+		return false
+	}
+	c.pending[serviceID] = &queuedUpgrade{run: run, superseded: superseded}
+	c.Unlock()
+
+	if queued != nil {
+		queued.superseded()
+	}
+	return true
+}
+
+func (c *upgradeCoordinator) start(serviceID string, run func()) {
+	for run != nil {
+		if c.slots != nil {
+			c.slots <- struct{}{}
+		}
+		run()
+		if c.slots != nil {
+			<-c.slots
+		}
+
+		c.Lock()
+		run = nil
+		if next := c.pending[serviceID]; next != nil {
+			run = next.run
+			c.pending[serviceID] = nil
+		} else {
+			delete(c.pending, serviceID)
+		}
+		c.Unlock()
+	}
+}
@@ -0,0 +1,77 @@
+package drivers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpgradeCoordinatorSupersedesQueuedUpgrade(t *testing.T) {
+	coordinator := newUpgradeCoordinator(0)
+	release := make(chan struct{})
+	ran := make(chan string, 3)
+	superseded := make(chan string, 3)
+
+	submit := func(name string, block bool) bool {
+		return coordinator.submit("1s1", func() {
+			ran <- name
+			if block {
+				<-release
+			}
+		}, func() {
+			superseded <- name
+		})
+	}
+
+	if submit("first", true) {
+		t.Fatal("First upgrade of a service should not queue")
+	}
+	expectReceive(t, ran, "first")
+
+	if !submit("second", false) || !submit("third", false) {
+		t.Fatal("Upgrades of a service already upgrading should queue")
+	}
+	expectReceive(t, superseded, "second")
+
+	close(release)
+	expectReceive(t, ran, "third")
+	select {
+	case name := <-ran:
+		t.Fatalf("Superseded upgrade %s should not run", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestUpgradeCoordinatorCapsConcurrentUpgrades(t *testing.T) {
+	coordinator := newUpgradeCoordinator(1)
+	release := make(chan struct{})
+	ran := make(chan string, 2)
+
+	coordinator.submit("1s1", func() {
+		ran <- "1s1"
+		<-release
+	}, func() {})
+	expectReceive(t, ran, "1s1")
+
+	if coordinator.submit("1s2", func() { ran <- "1s2" }, func() {}) {
+		t.Fatal("Upgrade of another service should not queue behind the service")
+	}
+	select {
+	case name := <-ran:
+		t.Fatalf("Upgrade of %s should wait for a free slot", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	expectReceive(t, ran, "1s2")
+}
+
+func expectReceive(t *testing.T, ch chan string, expected string) {
+	select {
+	case name := <-ch:
+		if name != expected {
+			t.Fatalf("Expected %s, got %s", expected, name)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timeout waiting for %s", expected)
+	}
+}
@@ -47,12 +47,25 @@ func main() {
 			),
 			EnvVar: "RSA_PRIVATE_KEY_CONTENTS",
 		},
+		cli.IntFlag{
+			Name:   "execution-history-size",
+			Usage:  "Number of executions kept in the delivery log of every receiver",
+			Value:  service.DefaultExecutionHistorySize,
+			EnvVar: "EXECUTION_HISTORY_SIZE",
+		},
+		cli.IntFlag{
+			Name:   "max-concurrent-upgrades",
+			Usage:  "Number of services the serviceUpgrade driver upgrades at the same time, 0 for no limit",
+			Value:  drivers.DefaultMaxConcurrentUpgrades,
+			EnvVar: "MAX_CONCURRENT_UPGRADES",
+		},
 	}
 	app.Run(os.Args)
 }
 
 func StartWebhook(c *cli.Context) {
 	drivers.RegisterDrivers()
+	drivers.SetMaxConcurrentUpgrades(c.GlobalInt("max-concurrent-upgrades"))
 	privateKey, publicKey, err := service.GetKeys(c)
 	if err != nil {
 		log.Fatal("rsa-private-key-file or rsa-public-key-file not provided, halting")
@@ -62,6 +75,7 @@ func StartWebhook(c *cli.Context) {
 		PrivateKey:    privateKey,
 		PublicKey:     publicKey,
 		ClientFactory: &service.ClientFactory{},
+		Executions:    service.NewExecutionLog(c.GlobalInt("execution-history-size")),
 	}
 	router := service.NewRouter(rh)
 	log.Infof("Webhook service listening on 8085")
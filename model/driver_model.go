@@ -2,35 +2,80 @@ package model
 
 //ScaleService driver
 type ScaleService struct {
-	ServiceID   string `json:"serviceId,omitempty" mapstructure:"serviceId"`
-	ScaleChange int64  `json:"amount,omitempty" mapstructure:"amount"`
-	ScaleAction string `json:"action,omitempty" mapstructure:"action"`
-	Min         int64  `json:"min,omitempty" mapstructure:"min"`
-	Max         int64  `json:"max,omitempty" mapstructure:"max"`
-	Type        string `json:"type,omitempty" mapstructure:"type"`
+	ServiceID         string            `json:"serviceId,omitempty" mapstructure:"serviceId"`
+	ServiceSelector   map[string]string `json:"serviceSelector,omitempty" mapstructure:"serviceSelector"`
+	ScaleChange       int64             `json:"amount,omitempty" mapstructure:"amount"`
+	ScaleAction       string            `json:"action,omitempty" mapstructure:"action"`
+	AmountType        string            `json:"amountType,omitempty" mapstructure:"amountType"`
+	ValuePath         string            `json:"valuePath,omitempty" mapstructure:"valuePath"`
+	Min               int64             `json:"min,omitempty" mapstructure:"min"`
+	Max               int64             `json:"max,omitempty" mapstructure:"max"`
+	PayloadFormat     string            `json:"payloadFormat,omitempty" mapstructure:"payloadFormat"`
+	ReverseOnResolved bool              `json:"reverseOnResolved,omitempty" mapstructure:"reverseOnResolved"`
+	CooldownSeconds   int64             `json:"cooldownSeconds,omitempty" mapstructure:"cooldownSeconds"`
+	BoundsBehavior    string            `json:"boundsBehavior,omitempty" mapstructure:"boundsBehavior"`
+	Type              string            `json:"type,omitempty" mapstructure:"type"`
+}
+
+//ScaleStack driver
+type ScaleStack struct {
+	StackID        string `json:"stackId,omitempty" mapstructure:"stackId"`
+	ScaleChange    int64  `json:"amount,omitempty" mapstructure:"amount"`
+	ScaleAction    string `json:"action,omitempty" mapstructure:"action"`
+	AmountType     string `json:"amountType,omitempty" mapstructure:"amountType"`
+	Min            int64  `json:"min,omitempty" mapstructure:"min"`
+	Max            int64  `json:"max,omitempty" mapstructure:"max"`
+	BoundsBehavior string `json:"boundsBehavior,omitempty" mapstructure:"boundsBehavior"`
+	Type           string `json:"type,omitempty" mapstructure:"type"`
 }
 
-//ServiceUpgrade driver
+//ServiceUpgrade driver, IntervalMillis is the pause between batches in seconds despite its name
 type ServiceUpgrade struct {
+	ServiceSelector       map[string]string `json:"serviceSelector,omitempty" mapstructure:"serviceSelector"`
+	Tag                   string            `json:"tag,omitempty" mapstructure:"tag"`
+	TagMatch              string            `json:"tagMatch,omitempty" mapstructure:"tagMatch"`
+	ImageRepositories     []string          `json:"imageRepositories,omitempty" mapstructure:"imageRepositories"`
+	PayloadFormat         string            `json:"payloadFormat,omitempty" mapstructure:"payloadFormat"`
+	BatchSize             int64             `json:"batchSize,omitempty" mapstructure:"batchSize"`
+	IntervalMillis        int64             `json:"intervalMillis,omitempty" mapstructure:"intervalMillis"`
+	StartFirst            bool              `json:"startFirst,omitempty" mapstructure:"startFirst"`
+	PinDigest             bool              `json:"pinDigest,omitempty" mapstructure:"pinDigest"`
+	AutoRollback          bool              `json:"autoRollback,omitempty" mapstructure:"autoRollback"`
+	UpgradeTimeoutSeconds int64             `json:"upgradeTimeoutSeconds,omitempty" mapstructure:"upgradeTimeoutSeconds"`
+	PollIntervalSeconds   int64             `json:"pollIntervalSeconds,omitempty" mapstructure:"pollIntervalSeconds"`
+	AutoFinish            string            `json:"autoFinish,omitempty" mapstructure:"autoFinish"`
+	Type                  string            `json:"type,omitempty" mapstructure:"type"`
+}
+
+//RestartService driver
+type RestartService struct {
+	ServiceID             string            `json:"serviceId,omitempty" mapstructure:"serviceId"`
+	ServiceSelector       map[string]string `json:"serviceSelector,omitempty" mapstructure:"serviceSelector"`
+	BatchSize             int64             `json:"batchSize,omitempty" mapstructure:"batchSize"`
+	IntervalSeconds       int64             `json:"intervalSeconds,omitempty" mapstructure:"intervalSeconds"`
+	RestartTimeoutSeconds int64             `json:"restartTimeoutSeconds,omitempty" mapstructure:"restartTimeoutSeconds"`
+	Type                  string            `json:"type,omitempty" mapstructure:"type"`
+}
+
+//RollbackService driver
+type RollbackService struct {
+	ServiceID       string            `json:"serviceId,omitempty" mapstructure:"serviceId"`
 	ServiceSelector map[string]string `json:"serviceSelector,omitempty" mapstructure:"serviceSelector"`
-	Tag             string            `json:"tag,omitempty" mapstructure:"tag"`
-	PayloadFormat   string            `json:"payloadFormat,omitempty" mapstructure:"payloadFormat"`
-	BatchSize       int64             `json:"batchSize,omitempty" mapstructure:"batchSize"`
-	IntervalMillis  int64             `json:"intervalMillis,omitempty" mapstructure:"intervalMillis"`
-	StartFirst      bool              `json:"startFirst,omitempty" mapstructure:"startFirst"`
 	Type            string            `json:"type,omitempty" mapstructure:"type"`
 }
 
 //ScaleHost driver
 type ScaleHost struct {
-	HostSelector   map[string]string `json:"hostSelector,omitempty" mapstructure:"hostSelector"`
-	HostTemplateID string            `json:"hostTemplateId,omitempty" mapstructure:"hostTemplateId"`
-	Amount         int64             `json:"amount,omitempty" mapstructure:"amount"`
-	Action         string            `json:"action,omitempty" mapstructure:"action"`
-	Min            int64             `json:"min,omitempty" mapstructure:"min"`
-	Max            int64             `json:"max,omitempty" mapstructure:"max"`
-	DeleteOption   string            `json:"deleteOption,omitempty" mapstructure:"deleteOption"`
-	Type           string            `json:"type,omitempty" mapstructure:"type"`
+	HostSelector    map[string]string `json:"hostSelector,omitempty" mapstructure:"hostSelector"`
+	HostTemplateID  string            `json:"hostTemplateId,omitempty" mapstructure:"hostTemplateId"`
+	Amount          int64             `json:"amount,omitempty" mapstructure:"amount"`
+	Action          string            `json:"action,omitempty" mapstructure:"action"`
+	Min             int64             `json:"min,omitempty" mapstructure:"min"`
+	Max             int64             `json:"max,omitempty" mapstructure:"max"`
+	DeleteOption    string            `json:"deleteOption,omitempty" mapstructure:"deleteOption"`
+	CooldownSeconds int64             `json:"cooldownSeconds,omitempty" mapstructure:"cooldownSeconds"`
+	BoundsBehavior  string            `json:"boundsBehavior,omitempty" mapstructure:"boundsBehavior"`
+	Type            string            `json:"type,omitempty" mapstructure:"type"`
 }
 
 //ForwardPost driver
@@ -13,17 +13,69 @@ type ServerAPIError struct {
 
 type Webhook struct {
 	v1client.Resource
-	URL                  string         `json:"url"`
-	Driver               string         `json:"driver"`
-	Name                 string         `json:"name"`
-	State                string         `json:"state"`
-	ScaleServiceConfig   ScaleService   `json:"scaleServiceConfig"`
-	ServiceUpgradeConfig ServiceUpgrade `json:"serviceUpgradeConfig"`
-	ScaleHostConfig      ScaleHost      `json:"scaleHostConfig"`
-	ForwardPostConfig    ForwardPost    `json:"forwardPostConfig"`
+	URL                   string          `json:"url"`
+	Driver                string          `json:"driver"`
+	Name                  string          `json:"name"`
+	State                 string          `json:"state"`
+	Secret                string          `json:"secret,omitempty"`
+	Filters               []Filter        `json:"filters"`
+	ScaleServiceConfig    ScaleService    `json:"scaleServiceConfig"`
+	ScaleStackConfig      ScaleStack      `json:"scaleStackConfig"`
+	ServiceUpgradeConfig  ServiceUpgrade  `json:"serviceUpgradeConfig"`
+	RestartServiceConfig  RestartService  `json:"restartServiceConfig"`
+	RollbackServiceConfig RollbackService `json:"rollbackServiceConfig"`
+	ScaleHostConfig       ScaleHost       `json:"scaleHostConfig"`
+	ForwardPostConfig     ForwardPost     `json:"forwardPostConfig"`
+}
+
+type Filter struct {
+	Source   string `json:"source"`
+	Path     string `json:"path"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
 }
 
 type WebhookCollection struct {
 	v1client.Collection
 	Data []Webhook `json:"data,omitempty"`
 }
+
+type RotateKeyInput struct {
+	GracePeriodSeconds int64 `json:"gracePeriodSeconds"`
+}
+
+type ReceiverExecution struct {
+	v1client.Resource
+	ReceiverID string `json:"receiverId"`
+	Driver     string `json:"driver"`
+	Created    string `json:"created"`
+	SourceIP   string `json:"sourceIp"`
+	Payload    string `json:"payload"`
+	StatusCode int    `json:"statusCode"`
+	Message    string `json:"message"`
+	Error      string `json:"error"`
+	JobID      string `json:"jobId"`
+}
+
+type ReceiverExecutionCollection struct {
+	v1client.Collection
+	Data []ReceiverExecution `json:"data,omitempty"`
+}
+
+type Job struct {
+	v1client.Resource
+	ReceiverID string      `json:"receiverId"`
+	Driver     string      `json:"driver"`
+	State      string      `json:"state"`
+	Created    string      `json:"created"`
+	Finished   string      `json:"finished"`
+	Message    string      `json:"message"`
+	Error      string      `json:"error"`
+	Results    []JobResult `json:"results"`
+}
+
+type JobResult struct {
+	ResourceID string `json:"resourceId"`
+	State      string `json:"state"`
+	Message    string `json:"message"`
+}
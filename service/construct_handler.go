@@ -74,20 +74,29 @@ func (rh *RouteHandler) ConstructPayload(w http.ResponseWriter, r *http.Request)
 		return code, err
 	}
 
+	code, err = validateFilters(wh.Filters)
+	if err != nil {
+		return code, err
+	}
+
 	uuid := uniuri.NewLen(40)
 
-	url := apiContext.UrlBuilder.Version("v1-webhooks")
-	url = url + "/endpoint?key=" + uuid + "&projectId=" + projectID
+	url := getExecuteURL(apiContext, uuid, projectID)
 
 	//saveWebhook needs only user fields
-	webhook, err := saveWebhook(uuid, wh.Name, wh.Driver, url, driverConfig, apiClient)
+	webhook, err := saveWebhook(uuid, wh.Name, wh.Driver, url, wh.Secret, wh.Filters, driverConfig, apiClient)
+	if err != nil {
+		return 500, err
+	}
+
+	filters, err := getFilters(*webhook)
 	if err != nil {
 		return 500, err
 	}
 
 	//needs only user fields
 	whResponse, err := newWebhook(apiContext, url, webhook.Id, wh.Driver, wh.Name, driverConfig, driver,
-		webhook.State, r)
+		webhook.State, filters, r)
 	if err != nil {
 		return 500, errors.Wrap(err, "Unable to create webhook response")
 	}
@@ -95,12 +104,19 @@ func (rh *RouteHandler) ConstructPayload(w http.ResponseWriter, r *http.Request)
 	return 200, nil
 }
 
-func saveWebhook(uuid string, name string, driver string, url string, config interface{}, apiClient *client.RancherClient) (*client.GenericObject, error) {
+func saveWebhook(uuid string, name string, driver string, url string, secret string, filters []model.Filter,
+	config interface{}, apiClient *client.RancherClient) (*client.GenericObject, error) {
 	resourceData := map[string]interface{}{
 		"url":    url,
 		"driver": driver,
 		"config": config,
 	}
+	if secret != "" {
+		resourceData["secret"] = secret
+	}
+	if len(filters) > 0 {
+		resourceData["filters"] = filtersToResourceData(filters)
+	}
 	obj, err := apiClient.GenericObject.Create(&client.GenericObject{
 		Name:         name,
 		Key:          uuid,
@@ -1,42 +1,61 @@
 package service
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"time"
 
+	"github.com/Sirupsen/logrus"
 	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/rancher/go-rancher/api"
 	"github.com/rancher/go-rancher/v2"
 	"github.com/rancher/webhook-service/drivers"
+	"github.com/rancher/webhook-service/model"
 )
 
 func (rh *RouteHandler) Execute(w http.ResponseWriter, r *http.Request) (int, error) {
-	jwtSigned := r.FormValue("token")
+	var job *drivers.Job
+	var code int
+	var err error
+
+	// the execute url carries the credentials, parsing the form would consume a form-encoded payload
+	// before its signature is verified
+	query := r.URL.Query()
+	jwtSigned := query.Get("token")
 	if jwtSigned != "" {
-		code, err := rh.ExecuteWithJwt(jwtSigned, r)
-		if err != nil {
-			return code, err
+		job, code, err = rh.ExecuteWithJwt(jwtSigned, r)
+	} else {
+		uuid := query.Get("key")
+		if uuid == "" {
+			return 400, fmt.Errorf("Invalid execute url, should have 'token' or 'key'")
 		}
-		return 200, nil
-	}
-	uuid := r.FormValue("key")
-	if uuid == "" {
-		return 400, fmt.Errorf("Invalid execute url, should have 'token' or 'key'")
-	}
 
-	projectID := r.FormValue("projectId")
-	if projectID == "" {
-		return 400, fmt.Errorf("Invalid execute url, url must contain projectId")
-	}
+		projectID := query.Get("projectId")
+		if projectID == "" {
+			return 400, fmt.Errorf("Invalid execute url, url must contain projectId")
+		}
 
-	code, err := rh.ExecuteWithKey(uuid, projectID, r)
+		job, code, err = rh.ExecuteWithKey(uuid, projectID, r)
+	}
 	if err != nil {
 		return code, err
 	}
 
+	if job != nil {
+		if code == http.StatusAccepted {
+			w.WriteHeader(code)
+		}
+		apiContext := api.GetApiContext(r)
+		apiContext.WriteResource(newJob(apiContext, job))
+	}
+
+	// the status code has been written along with the job
 	return 200, nil
 }
 
-func (rh *RouteHandler) ExecuteWithJwt(jwtSigned string, request *http.Request) (int, error) {
+func (rh *RouteHandler) ExecuteWithJwt(jwtSigned string, request *http.Request) (*drivers.Job, int, error) {
 	token, err := jwt.Parse(jwtSigned, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
@@ -45,102 +64,190 @@ func (rh *RouteHandler) ExecuteWithJwt(jwtSigned string, request *http.Request)
 	})
 
 	if err != nil || !token.Valid {
-		return 400, fmt.Errorf("Invalid token error: %v", err)
+		return nil, 400, fmt.Errorf("Invalid token error: %v", err)
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok {
 		driverID, ok := claims["driver"].(string)
 		if !ok {
-			return 400, fmt.Errorf("Driver not found after decode")
+			return nil, 400, fmt.Errorf("Driver not found after decode")
 		}
 
 		driver := drivers.GetDriver(driverID)
 		if driver == nil {
-			return 400, fmt.Errorf("Driver %s is not registered", driverID)
+			return nil, 400, fmt.Errorf("Driver %s is not registered", driverID)
 		}
 
 		projectID, ok := claims["projectId"].(string)
 		if !ok {
-			return 400, fmt.Errorf("ProjectId not provided by server")
+			return nil, 400, fmt.Errorf("ProjectId not provided by server")
 		}
 
 		uuid, ok := claims["uuid"].(string)
 		if !ok {
-			return 400, fmt.Errorf("Uuid not found after decode")
+			return nil, 400, fmt.Errorf("Uuid not found after decode")
 		}
 
 		apiClient, err := rh.ClientFactory.GetClient(projectID)
 		if err != nil {
-			return 500, err
+			return nil, 500, err
 		}
 
-		code, err := validateWebhook(uuid, apiClient)
+		webhook, code, err := findWebhookByKey(uuid, apiClient)
 		if err != nil {
-			return code, err
+			return nil, code, err
 		}
 
-		responseCode, err := driver.Execute(claims["config"], apiClient, request)
-		if err != nil {
-			return responseCode, fmt.Errorf("Error %v in executing driver for %s", err, driverID)
-		}
+		return rh.executeWebhook(webhook, projectID, driverID, driver, claims["config"], apiClient, request)
 	}
-	return 200, nil
+	return nil, 200, nil
 }
 
-func (rh *RouteHandler) ExecuteWithKey(uuid string, projectID string, request *http.Request) (int, error) {
+func (rh *RouteHandler) ExecuteWithKey(uuid string, projectID string, request *http.Request) (*drivers.Job, int, error) {
 	apiClient, err := rh.ClientFactory.GetClient(projectID)
 	if err != nil {
-		return 500, err
+		return nil, 500, err
 	}
 
-	filters := make(map[string]interface{})
-	filters["key"] = uuid
-	goCollection, err := apiClient.GenericObject.List(&client.ListOpts{
-		Filters: filters,
-	})
+	webhook, code, err := findWebhookByKey(uuid, apiClient)
 	if err != nil {
-		return 500, fmt.Errorf("Error %v filtering genericObjects by key", err)
-	}
-
-	if len(goCollection.Data) == 0 {
-		return 403, fmt.Errorf("Requested webhook has been revoked/does not exist for this account")
+		return nil, code, err
 	}
 
-	resourceData := goCollection.Data[0].ResourceData
+	resourceData := webhook.ResourceData
 	driverID, ok := resourceData["driver"].(string)
 	if !ok {
-		return 400, fmt.Errorf("No driver provided")
+		return nil, 400, fmt.Errorf("No driver provided")
 	}
 
 	driver := drivers.GetDriver(driverID)
 	if driver == nil {
-		return 400, fmt.Errorf("Driver %s is not registered", driverID)
+		return nil, 400, fmt.Errorf("Driver %s is not registered", driverID)
 	}
 
 	driverConfig, ok := resourceData["config"]
 	if !ok {
-		return 400, fmt.Errorf("Driver config not found")
+		return nil, 400, fmt.Errorf("Driver config not found")
+	}
+
+	return rh.executeWebhook(webhook, projectID, driverID, driver, driverConfig, apiClient, request)
+}
+
+// executeWebhook verifies the signature of the request, runs the driver of an active webhook as a job
+// and records the execution
+func (rh *RouteHandler) executeWebhook(webhook *client.GenericObject, projectID string, driverID string, driver drivers.WebhookDriver,
+	driverConfig interface{}, apiClient *client.RancherClient, request *http.Request) (*drivers.Job, int, error) {
+	var payload []byte
+	if request.Body != nil {
+		var err error
+		payload, err = ioutil.ReadAll(request.Body)
+		if err != nil {
+			return nil, 500, fmt.Errorf("Error reading request body: %v", err)
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(payload))
+	}
+
+	execution := model.ReceiverExecution{
+		ReceiverID: webhook.Id,
+		Driver:     driverID,
+		Created:    time.Now().UTC().Format(time.RFC3339),
+		SourceIP:   getSourceIP(request),
+		Payload:    truncatePayload(payload),
+	}
+
+	var job *drivers.Job
+	code, err := verifySignature(webhook, request.Header, payload)
+	if err == nil {
+		job, code, err = rh.runDriver(webhook, projectID, driverID, driver, driverConfig, apiClient, request, payload, &execution)
+	}
+	execution.StatusCode = code
+	execution.JobID = job.ID()
+	if err != nil {
+		execution.Error = err.Error()
+	}
+	rh.Executions.Record(execution)
+	return job, code, err
+}
+
+func (rh *RouteHandler) runDriver(webhook *client.GenericObject, projectID string, driverID string, driver drivers.WebhookDriver,
+	driverConfig interface{}, apiClient *client.RancherClient, request *http.Request, payload []byte,
+	execution *model.ReceiverExecution) (*drivers.Job, int, error) {
+	if webhookState(*webhook) == stateInactive {
+		logrus.Infof("Webhook %s is inactive, skipping execution", webhook.Id)
+		execution.Message = "Webhook is inactive, execution skipped"
+		return nil, 200, nil
+	}
+
+	filters, err := getFilters(*webhook)
+	if err != nil {
+		logrus.Errorf("Skipping execution of webhook %s: %v", webhook.Id, err)
+		execution.Message = "Stored filters can't be read, execution skipped"
+		return nil, 500, err
+	}
+	if matched, filter := matchFilters(filters, request.Header, payload); !matched {
+		logrus.Infof("Request to webhook %s doesn't match filter %s, skipping execution", webhook.Id, filter)
+		execution.Message = "Request doesn't match filter " + filter + ", execution skipped"
+		return nil, 200, nil
+	}
+
+	job := rh.Jobs.New(projectID, webhook.Id, driverID)
+	request = drivers.RequestWithJob(request, job)
+
+	if request.URL.Query().Get("async") == "true" {
+		go func() {
+			_, err := driver.Execute(driverConfig, apiClient, request)
+			if err != nil {
+				logrus.Errorf("Error %v in executing driver for %s", err, driverID)
+			}
+			job.Complete(err)
+		}()
+		return job, http.StatusAccepted, nil
 	}
 
 	responseCode, err := driver.Execute(driverConfig, apiClient, request)
+	job.Complete(err)
 	if err != nil {
-		return responseCode, fmt.Errorf("Error %v in executing driver for %s", err, driverID)
+		return job, responseCode, fmt.Errorf("Error %v in executing driver for %s", err, driverID)
 	}
 
-	return 200, nil
+	if job.Running() {
+		return job, http.StatusAccepted, nil
+	}
+	return job, 200, nil
 }
 
-func validateWebhook(uuid string, apiClient *client.RancherClient) (int, error) {
+// findWebhookByKey looks up the webhook owning key, including keys still within the grace period of a rotation.
+// Expired previous keys found along the way are deleted
+func findWebhookByKey(uuid string, apiClient *client.RancherClient) (*client.GenericObject, int, error) {
 	filters := make(map[string]interface{})
 	filters["key"] = uuid
 	webhookCollection, err := apiClient.GenericObject.List(&client.ListOpts{
 		Filters: filters,
 	})
 	if err != nil {
-		return 500, err
+		return nil, 500, fmt.Errorf("Error %v filtering genericObjects by key", err)
 	}
-	if len(webhookCollection.Data) > 0 {
-		return 0, nil
+
+	now := time.Now()
+	for i, obj := range webhookCollection.Data {
+		if obj.Kind != previousKeyKind {
+			return &webhookCollection.Data[i], 0, nil
+		}
+		if !isValidPreviousKey(obj, now) {
+			if err := apiClient.GenericObject.Delete(&webhookCollection.Data[i]); err != nil {
+				logrus.Warnf("Error %v deleting expired key of webhook %s", err, obj.Name)
+			}
+			continue
+		}
+		receiverID, _ := obj.ResourceData["receiverId"].(string)
+		webhook, err := apiClient.GenericObject.ById(receiverID)
+		if err != nil {
+			return nil, 500, fmt.Errorf("Error %v getting webhook %s", err, receiverID)
+		}
+		if webhook != nil {
+			return webhook, 0, nil
+		}
 	}
-	return 403, fmt.Errorf("Requested webhook has been revoked")
+
+	return nil, 403, fmt.Errorf("Requested webhook has been revoked/does not exist for this account")
 }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rancher/webhook-service/model"
+)
+
+const (
+	DefaultExecutionHistorySize = 50
+	maxExecutionPayloadLength   = 1024
+)
+
+// ExecutionLog keeps the most recent executions of every receiver in memory
+type ExecutionLog struct {
+	sync.Mutex
+	size       int
+	lastID     int64
+	executions map[string][]model.ReceiverExecution
+}
+
+func NewExecutionLog(size int) *ExecutionLog {
+	if size <= 0 {
+		size = DefaultExecutionHistorySize
+	}
+	return &ExecutionLog{
+		size:       size,
+		executions: map[string][]model.ReceiverExecution{},
+	}
+}
+
+func (l *ExecutionLog) Record(execution model.ReceiverExecution) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.lastID++
+	execution.Id = strconv.FormatInt(l.lastID, 10)
+	execution.Type = "receiverExecution"
+
+	executions := append(l.executions[execution.ReceiverID], execution)
+	if len(executions) > l.size {
+		executions = executions[len(executions)-l.size:]
+	}
+	l.executions[execution.ReceiverID] = executions
+}
+
+// List returns the executions of a receiver, most recent first
+func (l *ExecutionLog) List(receiverID string) []model.ReceiverExecution {
+	l.Lock()
+	defer l.Unlock()
+
+	executions := l.executions[receiverID]
+	result := make([]model.ReceiverExecution, 0, len(executions))
+	for i := len(executions) - 1; i >= 0; i-- {
+		result = append(result, executions[i])
+	}
+	return result
+}
+
+func (l *ExecutionLog) Remove(receiverID string) {
+	l.Lock()
+	defer l.Unlock()
+	delete(l.executions, receiverID)
+}
+
+func getSourceIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func truncatePayload(payload []byte) string {
+	if len(payload) > maxExecutionPayloadLength {
+		return string(payload[:maxExecutionPayloadLength]) + "..."
+	}
+	return string(payload)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestWebhookExecutions(t *testing.T) {
+	wh := createReceiver(t, scaleServiceReceiver)
+	if !strings.HasSuffix(wh.Links["executions"], "/v1-webhooks/receivers/1/executions?projectId=1a1") {
+		t.Fatalf("Bad executions URL: %v", wh.Links["executions"])
+	}
+
+	// Test executing the webhook is recorded
+	requestExecute, err := http.NewRequest("POST", wh.URL, bytes.NewBuffer([]byte(`{"foo":"bar"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestExecute.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+	response := httptest.NewRecorder()
+	handler := HandleError(schemas, r.Execute)
+	handler.ServeHTTP(response, requestExecute)
+	if response.Code != 200 {
+		t.Errorf("StatusCode %d means execute failed", response.Code)
+	}
+
+	request, err := http.NewRequest("GET", wh.Links["executions"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means list executions failed", response.Code)
+	}
+	resp, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executions := &model.ReceiverExecutionCollection{}
+	err = json.Unmarshal(resp, executions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(executions.Data) != 1 {
+		t.Fatalf("Expected 1 execution, got %v", len(executions.Data))
+	}
+	execution := executions.Data[0]
+	if execution.ReceiverID != "1" || execution.Driver != "scaleService" || execution.StatusCode != 200 ||
+		execution.SourceIP != "10.0.0.1" || execution.Payload != `{"foo":"bar"}` || execution.Error != "" || execution.Created == "" {
+		t.Fatalf("Unexpected execution: %#v", execution)
+	}
+
+	deleteReceiver(t, wh.Id)
+	if len(r.Executions.List("1")) != 0 {
+		t.Fatal("Executions not removed on delete")
+	}
+}
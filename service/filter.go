@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/drivers"
+	"github.com/rancher/webhook-service/model"
+)
+
+const (
+	filterSourceBody     = "body"
+	filterSourceHeader   = "header"
+	filterOperatorEquals = "equals"
+	filterOperatorRegex  = "regex"
+	filterOperatorExists = "exists"
+)
+
+var (
+	filterSources   = []string{filterSourceBody, filterSourceHeader}
+	filterOperators = []string{filterOperatorEquals, filterOperatorRegex, filterOperatorExists}
+)
+
+func validateFilters(filters []model.Filter) (int, error) {
+	for i, filter := range filters {
+		if filter.Path == "" {
+			return 400, fmt.Errorf("Filter %d: path not provided", i)
+		}
+		source := filterSource(filter)
+		if source != filterSourceBody && source != filterSourceHeader {
+			return 400, fmt.Errorf("Filter %d: invalid source %s, must be one of %v", i, filter.Source, filterSources)
+		}
+		switch filterOperator(filter) {
+		case filterOperatorEquals, filterOperatorExists:
+		case filterOperatorRegex:
+			if _, err := regexp.Compile(filter.Value); err != nil {
+				return 400, fmt.Errorf("Filter %d: invalid regex %s: %v", i, filter.Value, err)
+			}
+		default:
+			return 400, fmt.Errorf("Filter %d: invalid operator %s, must be one of %v", i, filter.Operator, filterOperators)
+		}
+	}
+	return 0, nil
+}
+
+// matchFilters evaluates all filters against the request, returning a description of the first one not matching
+func matchFilters(filters []model.Filter, header http.Header, payload []byte) (bool, string) {
+	var body interface{}
+	bodyDecoded := false
+	for _, filter := range filters {
+		var value interface{}
+		var found bool
+		if filterSource(filter) == filterSourceHeader {
+			if values, ok := header[http.CanonicalHeaderKey(filter.Path)]; ok && len(values) > 0 {
+				value, found = values[0], true
+			}
+		} else {
+			if !bodyDecoded {
+				bodyDecoded = true
+				if err := json.Unmarshal(payload, &body); err != nil {
+					logrus.Infof("Request body is not JSON, body filters can't match: %v", err)
+				}
+			}
+			value, found = drivers.LookupPath(body, filter.Path)
+		}
+
+		if !matchFilter(filter, value, found) {
+			return false, fmt.Sprintf("%s %s %s %s", filterSource(filter), filter.Path, filterOperator(filter), filter.Value)
+		}
+	}
+	return true, ""
+}
+
+func matchFilter(filter model.Filter, value interface{}, found bool) bool {
+	if !found {
+		return false
+	}
+	switch filterOperator(filter) {
+	case filterOperatorExists:
+		return true
+	case filterOperatorRegex:
+		matched, err := regexp.MatchString(filter.Value, filterValueString(value))
+		return err == nil && matched
+	default:
+		return filterValueString(value) == filter.Value
+	}
+}
+
+func filterValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(bytes)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func filterSource(filter model.Filter) string {
+	if filter.Source == "" {
+		return filterSourceBody
+	}
+	return filter.Source
+}
+
+func filterOperator(filter model.Filter) string {
+	if filter.Operator == "" {
+		return filterOperatorEquals
+	}
+	return filter.Operator
+}
+
+// filtersToResourceData converts filters to the generic form they are stored in on the webhook
+func filtersToResourceData(filters []model.Filter) []interface{} {
+	data := []interface{}{}
+	for _, filter := range filters {
+		data = append(data, map[string]interface{}{
+			"source":   filterSource(filter),
+			"path":     filter.Path,
+			"operator": filterOperator(filter),
+			"value":    filter.Value,
+		})
+	}
+	return data
+}
+
+// getFilters decodes the filters stored on the webhook. Filters that can't be decoded are an error,
+// executions must not run unfiltered because of them
+func getFilters(genericObject client.GenericObject) ([]model.Filter, error) {
+	var filters []model.Filter
+	data, ok := genericObject.ResourceData["filters"]
+	if !ok || data == nil {
+		return filters, nil
+	}
+	if err := mapstructure.Decode(data, &filters); err != nil {
+		return nil, fmt.Errorf("Couldn't read filters of webhook %s: %v", genericObject.Id, err)
+	}
+	return filters, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/webhook-service/drivers"
+)
+
+func TestWebhookFilters(t *testing.T) {
+	// Test invalid filters are rejected
+	response := postReceiver(t, `{"driver":"scaleService","name":"wh-name",
+		"filters": [{"path": "status", "operator": "regex", "value": "("}],
+		"scaleServiceConfig": {"serviceId": "id", "amount": 1, "action": "up", "min": 1, "max": 4}}`)
+	if response.Code != 400 {
+		t.Fatalf("StatusCode %d, invalid regex filter should be rejected", response.Code)
+	}
+
+	wh := createReceiver(t, `{"driver":"scaleService","name":"wh-name",
+		"filters": [{"path": "status", "value": "firing"},
+			{"path": "alerts.0.labels.alertname", "operator": "exists"},
+			{"source": "header", "path": "User-Agent", "operator": "regex", "value": "^Alertmanager/"}],
+		"scaleServiceConfig": {"serviceId": "id", "amount": 1, "action": "up", "min": 1, "max": 4}}`)
+	if len(wh.Filters) != 3 || wh.Filters[0].Source != "body" || wh.Filters[0].Operator != "equals" {
+		t.Fatalf("Unexpected filters: %#v", wh.Filters)
+	}
+
+	tests := []struct {
+		body      string
+		userAgent string
+		executed  bool
+	}{
+		{`{"status": "firing", "alerts": [{"labels": {"alertname": "HighLoad"}}]}`, "Alertmanager/0.15.0", true},
+		{`{"status": "resolved", "alerts": [{"labels": {"alertname": "HighLoad"}}]}`, "Alertmanager/0.15.0", false},
+		{`{"status": "firing", "alerts": [{"labels": {}}]}`, "Alertmanager/0.15.0", false},
+		{`{"status": "firing", "alerts": [{"labels": {"alertname": "HighLoad"}}]}`, "curl/7.0", false},
+		{`not json`, "Alertmanager/0.15.0", false},
+	}
+	mockDriver := drivers.Drivers["scaleService"].(*MockServiceDriver)
+	for _, test := range tests {
+		executions := mockDriver.executions
+		request, err := http.NewRequest("POST", wh.URL, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("User-Agent", test.userAgent)
+		response := httptest.NewRecorder()
+		handler := HandleError(schemas, r.Execute)
+		handler.ServeHTTP(response, request)
+		if response.Code != 200 {
+			t.Fatalf("StatusCode %d means execute failed", response.Code)
+		}
+		if executed := mockDriver.executions != executions; executed != test.executed {
+			t.Fatalf("Execution of %s with %s: %v, expected %v", test.body, test.userAgent, executed, test.executed)
+		}
+	}
+
+	// Test filters are removed by updating with an empty list
+	byID := fmt.Sprintf("%s/v1-webhooks/receivers/1?projectId=1a1", server.URL)
+	request, err := http.NewRequest("PUT", byID, bytes.NewBuffer([]byte(`{"filters": [],
+		"scaleServiceConfig": {"serviceId": "id", "amount": 1, "action": "up", "min": 1, "max": 4}}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means update failed", response.Code)
+	}
+	wh = decodeWebhook(t, response)
+	if len(wh.Filters) != 0 {
+		t.Fatalf("Unexpected filters after update: %#v", wh.Filters)
+	}
+
+	deleteReceiver(t, wh.Id)
+}
+
+func TestWebhookUndecodableFilters(t *testing.T) {
+	wh := createReceiver(t, scaleServiceReceiver)
+	mock := r.ClientFactory.(*MockRancherClientFactory).mw
+	mock.created[wh.Id].ResourceData["filters"] = "status=firing"
+
+	mockDriver := drivers.Drivers["scaleService"].(*MockServiceDriver)
+	executions := mockDriver.executions
+	request, err := http.NewRequest("POST", wh.URL, strings.NewReader(`{"status": "firing"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := httptest.NewRecorder()
+	handler := HandleError(schemas, r.Execute)
+	handler.ServeHTTP(response, request)
+	if response.Code != 500 {
+		t.Fatalf("StatusCode %d, execution with undecodable filters should fail", response.Code)
+	}
+	if mockDriver.executions != executions {
+		t.Fatal("Driver executed without its filters")
+	}
+	recorded := r.Executions.List(wh.Id)
+	if len(recorded) == 0 || recorded[0].Message != "Stored filters can't be read, execution skipped" || recorded[0].Error == "" {
+		t.Fatalf("Unexpected executions: %#v", recorded)
+	}
+
+	deleteReceiver(t, wh.Id)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -34,6 +35,16 @@ func init() {
 	}
 	drivers.Drivers["scaleService"] = &MockServiceDriver{expectedConfig: expectedServiceConfig}
 
+	expectedStackConfig := model.ScaleStack{
+		StackID:     "1st1",
+		ScaleAction: "up",
+		ScaleChange: 50,
+		AmountType:  "percentage",
+		Min:         1,
+		Max:         4,
+	}
+	drivers.Drivers["scaleStack"] = &MockStackDriver{expectedConfig: expectedStackConfig}
+
 	ServiceSelector := make(map[string]string)
 	ServiceSelector["foo"] = "bar"
 	expectedUpgradeServiceConfig := model.ServiceUpgrade{
@@ -45,6 +56,18 @@ func init() {
 	}
 	drivers.Drivers["serviceUpgrade"] = &MockUpgradeServiceDriver{expectedConfig: expectedUpgradeServiceConfig}
 
+	expectedRestartServiceConfig := model.RestartService{
+		ServiceSelector: map[string]string{"foo": "bar"},
+		BatchSize:       1,
+		IntervalSeconds: 2,
+	}
+	drivers.Drivers["restartService"] = &MockRestartServiceDriver{expectedConfig: expectedRestartServiceConfig}
+
+	expectedRollbackServiceConfig := model.RollbackService{
+		ServiceSelector: map[string]string{"foo": "bar"},
+	}
+	drivers.Drivers["rollbackService"] = &MockRollbackServiceDriver{expectedConfig: expectedRollbackServiceConfig}
+
 	HostSelector := make(map[string]string)
 	HostSelector["foo"] = "bar"
 	expectedHostConfigLabel := model.ScaleHost{
@@ -92,6 +115,57 @@ func init() {
 	server = httptest.NewServer(router)
 }
 
+// scaleServiceReceiver is the body of a plain receiver for tests of the receiver handlers
+const scaleServiceReceiver = `{"driver":"scaleService","name":"wh-name",
+	"scaleServiceConfig": {"serviceId": "id", "amount": 1, "action": "up", "min": 1, "max": 4}}`
+
+func postReceiver(t *testing.T, body string) *httptest.ResponseRecorder {
+	constructURL := fmt.Sprintf("%s/v1-webhooks/receivers?projectId=1a1", server.URL)
+	request, err := http.NewRequest("POST", constructURL, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	handler := HandleError(schemas, r.ConstructPayload)
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+func createReceiver(t *testing.T, body string) *model.Webhook {
+	response := postReceiver(t, body)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means ConstructPayloadTest failed", response.Code)
+	}
+	return decodeWebhook(t, response)
+}
+
+func decodeWebhook(t *testing.T, response *httptest.ResponseRecorder) *model.Webhook {
+	resp, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh := &model.Webhook{}
+	err = json.Unmarshal(resp, wh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wh
+}
+
+func deleteReceiver(t *testing.T, id string) {
+	byID := fmt.Sprintf("%s/v1-webhooks/receivers/%s?projectId=1a1", server.URL, id)
+	request, err := http.NewRequest("DELETE", byID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 204 {
+		t.Fatalf("StatusCode %d means delete failed", response.Code)
+	}
+}
+
 func TestMissingProjectIdHeader(t *testing.T) {
 	constructURL := fmt.Sprintf("%s/v1-webhooks", server.URL)
 	request, err := http.NewRequest("POST", constructURL, bytes.NewBuffer([]byte(`{}`)))
@@ -149,12 +223,17 @@ func (e *MockRancherClientFactory) GetClient(projectID string) (*client.RancherC
 type mockGenericObject struct {
 	client.GenericObjectOperations
 	created map[string]*client.GenericObject
+	keys    int
 }
 
 func (m *mockGenericObject) Create(webhook *client.GenericObject) (*client.GenericObject, error) {
 	webhook.Links = make(map[string]string)
 	webhook.Links["self"] = "self"
 	webhook.Id = "1"
+	if webhook.Kind == previousKeyKind {
+		m.keys++
+		webhook.Id = fmt.Sprintf("1go%d", m.keys)
+	}
 	m.created[webhook.Id] = webhook
 	return webhook, nil
 }
@@ -162,11 +241,41 @@ func (m *mockGenericObject) Create(webhook *client.GenericObject) (*client.Gener
 func (m *mockGenericObject) List(opts *client.ListOpts) (*client.GenericObjectCollection, error) {
 	webhooks := []client.GenericObject{}
 	for _, wh := range m.created {
+		if name, ok := opts.Filters["name"]; ok && name != wh.Name {
+			continue
+		}
+		if key, ok := opts.Filters["key"]; ok && key != wh.Key {
+			continue
+		}
+		if kind, ok := opts.Filters["kind"]; ok && kind != wh.Kind {
+			continue
+		}
 		webhooks = append(webhooks, *wh)
 	}
 	return &client.GenericObjectCollection{Data: webhooks}, nil
 }
 
+func (m *mockGenericObject) Update(existing *client.GenericObject, updates interface{}) (*client.GenericObject, error) {
+	update, ok := updates.(*client.GenericObject)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected update %#v", updates)
+	}
+	wh, ok := m.created[existing.Id]
+	if !ok {
+		return nil, fmt.Errorf("Doesn't exist")
+	}
+	if update.Name != "" {
+		wh.Name = update.Name
+	}
+	if update.Key != "" {
+		wh.Key = update.Key
+	}
+	if update.ResourceData != nil {
+		wh.ResourceData = update.ResourceData
+	}
+	return wh, nil
+}
+
 func (m *mockGenericObject) ById(id string) (*client.GenericObject, error) {
 	fmt.Printf("%v %#v\n\n", id, m.created)
 	if wh, ok := m.created[id]; ok {
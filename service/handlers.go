@@ -1,8 +1,11 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
@@ -14,6 +17,11 @@ import (
 	"github.com/rancher/webhook-service/model"
 )
 
+const (
+	stateActive   = "active"
+	stateInactive = "inactive"
+)
+
 func (rh *RouteHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) (int, error) {
 	logrus.Infof("Listing webhooks")
 	apiContext := api.GetApiContext(r)
@@ -44,7 +52,7 @@ func (rh *RouteHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) (in
 			continue
 		}
 		respWebhook, err := newWebhook(apiContext, webhook.URL, webhook.ID, webhook.Driver, webhook.Name,
-			webhook.Config, driver, webhook.State, r)
+			webhook.Config, driver, webhook.State, webhook.Filters, r)
 		if err != nil {
 			logrus.Warnf("Skipping webhook %s an error ocurred while producing response: %v", webhook.ID, err)
 			continue
@@ -96,7 +104,189 @@ func (rh *RouteHandler) GetWebhook(w http.ResponseWriter, r *http.Request) (int,
 	}
 
 	respWebhook, err := newWebhook(apiContext, webhook.URL, webhook.ID, webhook.Driver, webhook.Name,
-		webhook.Config, driver, webhook.State, r)
+		webhook.Config, driver, webhook.State, webhook.Filters, r)
+	if err != nil {
+		return 500, errors.Wrap(err, "Unable to create webhook response")
+	}
+
+	apiContext.WriteResource(respWebhook)
+	return 200, nil
+}
+
+func (rh *RouteHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) (int, error) {
+	apiContext := api.GetApiContext(r)
+	vars := mux.Vars(r)
+	webhookID := vars["id"]
+	logrus.Infof("Updating webhook %v", webhookID)
+
+	requestBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return 500, err
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		return 400, fmt.Errorf("Content-Type must be supplied as header. Only application/json is supported")
+	}
+
+	projectID, errCode, err := getProjectID(r)
+	if err != nil {
+		return errCode, err
+	}
+
+	wh := &model.Webhook{}
+	if err := json.Unmarshal(requestBytes, wh); err != nil {
+		return 400, errors.Wrap(err, "Bad request body")
+	}
+
+	apiClient, err := rh.ClientFactory.GetClient(projectID)
+	if err != nil {
+		return 500, err
+	}
+	obj, err := apiClient.GenericObject.ById(webhookID)
+	if err != nil {
+		return 500, err
+	}
+
+	if obj == nil {
+		return 404, fmt.Errorf("Webhook not found")
+	}
+
+	webhook, err := rh.convertToWebhookGenericObject(*obj)
+	if err != nil {
+		return 500, err
+	}
+
+	if wh.Driver != "" && wh.Driver != webhook.Driver {
+		return 400, fmt.Errorf("Driver of an existing webhook cannot be changed")
+	}
+	wh.Driver = webhook.Driver
+
+	driver := drivers.GetDriver(webhook.Driver)
+	if driver == nil {
+		return 400, fmt.Errorf("Can't find driver %v", webhook.Driver)
+	}
+
+	driverConfig := getDriverConfig(wh)
+	if driverConfig == nil {
+		return 400, fmt.Errorf("Invalid driver %v", wh.Driver)
+	}
+
+	if wh.Name == "" {
+		wh.Name = webhook.Name
+	}
+
+	if wh.Name != webhook.Name {
+		code, err := rh.isUniqueName(wh.Name, projectID, apiClient)
+		if err != nil {
+			return code, err
+		}
+	}
+
+	code, err := driver.ValidatePayload(driverConfig, apiClient)
+	if err != nil {
+		return code, err
+	}
+
+	code, err = validateFilters(wh.Filters)
+	if err != nil {
+		return code, err
+	}
+
+	//key and url are left untouched so that integrations using the webhook keep working
+	resourceData := map[string]interface{}{}
+	for k, v := range obj.ResourceData {
+		resourceData[k] = v
+	}
+	resourceData["config"] = driverConfig
+	//the secret is write only, it is replaced only when a new one is supplied and removed by an explicit null
+	if wh.Secret != "" {
+		resourceData["secret"] = wh.Secret
+	} else if isExplicitNull(requestBytes, "secret") {
+		delete(resourceData, "secret")
+	}
+	//filters are replaced only when supplied, an empty list removes them
+	filters := webhook.Filters
+	if wh.Filters != nil {
+		filters = wh.Filters
+		resourceData["filters"] = filtersToResourceData(wh.Filters)
+	}
+
+	obj, err = apiClient.GenericObject.Update(obj, &client.GenericObject{
+		Name:         wh.Name,
+		ResourceData: resourceData,
+	})
+	if err != nil {
+		return 500, fmt.Errorf("Failed to update webhook: %v", err)
+	}
+
+	respWebhook, err := newWebhook(apiContext, webhook.URL, webhook.ID, webhook.Driver, wh.Name,
+		driverConfig, driver, webhook.State, filters, r)
+	if err != nil {
+		return 500, errors.Wrap(err, "Unable to create webhook response")
+	}
+
+	apiContext.WriteResource(respWebhook)
+	return 200, nil
+}
+
+func (rh *RouteHandler) ActivateWebhook(w http.ResponseWriter, r *http.Request) (int, error) {
+	return rh.setWebhookState(r, stateActive)
+}
+
+func (rh *RouteHandler) DeactivateWebhook(w http.ResponseWriter, r *http.Request) (int, error) {
+	return rh.setWebhookState(r, stateInactive)
+}
+
+func (rh *RouteHandler) setWebhookState(r *http.Request, state string) (int, error) {
+	apiContext := api.GetApiContext(r)
+	vars := mux.Vars(r)
+	webhookID := vars["id"]
+	logrus.Infof("Setting state of webhook %v to %v", webhookID, state)
+
+	projectID, errCode, err := getProjectID(r)
+	if err != nil {
+		return errCode, err
+	}
+	apiClient, err := rh.ClientFactory.GetClient(projectID)
+	if err != nil {
+		return 500, err
+	}
+	obj, err := apiClient.GenericObject.ById(webhookID)
+	if err != nil {
+		return 500, err
+	}
+
+	if obj == nil {
+		return 404, fmt.Errorf("Webhook not found")
+	}
+
+	webhook, err := rh.convertToWebhookGenericObject(*obj)
+	if err != nil {
+		return 500, err
+	}
+
+	driver := drivers.GetDriver(webhook.Driver)
+	if driver == nil {
+		return 400, fmt.Errorf("Can't find driver %v", webhook.Driver)
+	}
+
+	if webhook.State != state {
+		resourceData := map[string]interface{}{}
+		for k, v := range obj.ResourceData {
+			resourceData[k] = v
+		}
+		resourceData["state"] = state
+
+		if _, err = apiClient.GenericObject.Update(obj, &client.GenericObject{
+			ResourceData: resourceData,
+		}); err != nil {
+			return 500, fmt.Errorf("Failed to update webhook: %v", err)
+		}
+	}
+
+	respWebhook, err := newWebhook(apiContext, webhook.URL, webhook.ID, webhook.Driver, webhook.Name,
+		webhook.Config, driver, state, webhook.Filters, r)
 	if err != nil {
 		return 500, errors.Wrap(err, "Unable to create webhook response")
 	}
@@ -105,6 +295,50 @@ func (rh *RouteHandler) GetWebhook(w http.ResponseWriter, r *http.Request) (int,
 	return 200, nil
 }
 
+func (rh *RouteHandler) ListExecutions(w http.ResponseWriter, r *http.Request) (int, error) {
+	apiContext := api.GetApiContext(r)
+	vars := mux.Vars(r)
+	webhookID := vars["id"]
+	logrus.Infof("Listing executions of webhook %v", webhookID)
+
+	projectID, errCode, err := getProjectID(r)
+	if err != nil {
+		return errCode, err
+	}
+	apiClient, err := rh.ClientFactory.GetClient(projectID)
+	if err != nil {
+		return 500, err
+	}
+	obj, err := apiClient.GenericObject.ById(webhookID)
+	if err != nil {
+		return 500, err
+	}
+
+	if obj == nil {
+		return 404, fmt.Errorf("Webhook not found")
+	}
+
+	collectionURL := apiContext.UrlBuilder.Current() + "?projectId=" + projectID
+	receiverURL := apiContext.UrlBuilder.ReferenceByIdLink("receiver", webhookID) + "?projectId=" + projectID
+	executions := rh.Executions.List(webhookID)
+	for i := range executions {
+		executions[i].Links = map[string]string{
+			"self":     collectionURL,
+			"receiver": receiverURL,
+		}
+		if executions[i].JobID != "" {
+			executions[i].Links["job"] = apiContext.UrlBuilder.ReferenceByIdLink("job", executions[i].JobID) + "?projectId=" + projectID
+		}
+	}
+
+	apiContext.Write(&model.ReceiverExecutionCollection{
+		Collection: v1client.Collection{
+			ResourceType: "receiverExecution",
+			Links:        map[string]string{"self": collectionURL}},
+		Data: executions})
+	return 200, nil
+}
+
 func (rh *RouteHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) (int, error) {
 	vars := mux.Vars(r)
 	webhookID := vars["id"]
@@ -132,9 +366,26 @@ func (rh *RouteHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) (i
 		statusCode := err.(*client.ApiError).StatusCode
 		return statusCode, err
 	}
+	if err := prunePreviousKeys(apiClient, webhookID, time.Time{}); err != nil {
+		logrus.Warnf("Error deleting previous keys of webhook %s: %v", webhookID, err)
+	}
+	if err := drivers.DeleteCooldown(apiClient, webhookID); err != nil {
+		logrus.Warnf("Error deleting cooldown of webhook %s: %v", webhookID, err)
+	}
+	rh.Executions.Remove(webhookID)
 	return 204, nil
 }
 
+// isExplicitNull reports whether the JSON object in body sets field to null
+func isExplicitNull(body []byte, field string) bool {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return false
+	}
+	value, ok := fields[field]
+	return ok && string(value) == "null"
+}
+
 func getProjectID(r *http.Request) (string, int, error) {
 	projectID := r.URL.Query().Get("projectId")
 	if projectID == "" {
@@ -145,38 +396,52 @@ func getProjectID(r *http.Request) (string, int, error) {
 }
 
 func newWebhook(context *api.ApiContext, url string, id string, driverName string, name string,
-	driverConfig interface{}, driver drivers.WebhookDriver, state string, r *http.Request) (*model.Webhook, error) {
+	driverConfig interface{}, driver drivers.WebhookDriver, state string, filters []model.Filter, r *http.Request) (*model.Webhook, error) {
 
 	selfLink := context.UrlBuilder.ReferenceByIdLink("receiver", id)
+	executionsLink := selfLink + "/executions"
+	actionLink := selfLink + "?action="
 	projectID := r.URL.Query().Get("projectId")
 	if projectID != "" {
 		selfLink = selfLink + "?projectId=" + projectID
+		executionsLink = executionsLink + "?projectId=" + projectID
+		actionLink = selfLink + "&action="
+	}
+
+	actions := map[string]string{"rotateKey": actionLink + "rotateKey"}
+	if state == stateInactive {
+		actions["activate"] = actionLink + "activate"
+	} else {
+		actions["deactivate"] = actionLink + "deactivate"
 	}
 
 	webhook := &model.Webhook{
 		Resource: v1client.Resource{
-			Id:    id,
-			Type:  "receiver",
-			Links: map[string]string{"self": selfLink},
+			Id:      id,
+			Type:    "receiver",
+			Links:   map[string]string{"self": selfLink, "executions": executionsLink},
+			Actions: actions,
 		},
-		URL:    url,
-		Driver: driverName,
-		Name:   name,
-		State:  state,
+		URL:     url,
+		Driver:  driverName,
+		Name:    name,
+		State:   state,
+		Filters: filters,
 	}
 	driver.ConvertToConfigAndSetOnWebhook(driverConfig, webhook)
 	return webhook, nil
 }
 
 type webhookGenericObject struct {
-	ID     string
-	Name   string
-	State  string
-	Links  map[string]string
-	Driver string
-	URL    string
-	Key    string
-	Config interface{}
+	ID      string
+	Name    string
+	State   string
+	Links   map[string]string
+	Driver  string
+	URL     string
+	Key     string
+	Config  interface{}
+	Filters []model.Filter
 }
 
 func (rh *RouteHandler) convertToWebhookGenericObject(genericObject client.GenericObject) (webhookGenericObject, error) {
@@ -195,21 +460,37 @@ func (rh *RouteHandler) convertToWebhookGenericObject(genericObject client.Gener
 		return webhookGenericObject{}, fmt.Errorf("Couldn't read webhook data. Bad config on resource")
 	}
 
+	//the webhook is still shown and can be updated with valid filters, executions are skipped until then
+	filters, err := getFilters(genericObject)
+	if err != nil {
+		logrus.Warn(err)
+	}
+
 	return webhookGenericObject{
-		Name:   genericObject.Name,
-		ID:     genericObject.Id,
-		State:  genericObject.State,
-		Links:  genericObject.Links,
-		Driver: d,
-		URL:    url,
-		Key:    genericObject.Key,
-		Config: config,
+		Name:    genericObject.Name,
+		ID:      genericObject.Id,
+		State:   webhookState(genericObject),
+		Links:   genericObject.Links,
+		Driver:  d,
+		URL:     url,
+		Key:     genericObject.Key,
+		Config:  config,
+		Filters: filters,
 	}, nil
 }
 
+// webhookState returns the state set through the activate/deactivate actions, falling back to the state of the generic object
+func webhookState(genericObject client.GenericObject) string {
+	if state, ok := genericObject.ResourceData["state"].(string); ok && state != "" {
+		return state
+	}
+	return genericObject.State
+}
+
 func (rh *RouteHandler) isUniqueName(webhookName string, projectID string, apiClient *client.RancherClient) (int, error) {
 	filters := make(map[string]interface{})
 	filters["name"] = webhookName
+	filters["kind"] = "webhookReceiver"
 	obj, err := apiClient.GenericObject.List(&client.ListOpts{
 		Filters: filters,
 	})
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/webhook-service/drivers"
+)
+
+func TestWebhookUpdate(t *testing.T) {
+	created := createReceiver(t, scaleServiceReceiver)
+
+	// Test updating the name and config of the webhook
+	byID := fmt.Sprintf("%s/v1-webhooks/receivers/1?projectId=1a1", server.URL)
+	jsonStr := []byte(`{"name":"wh-name-updated",
+		"scaleServiceConfig": {"serviceId": "id", "amount": 1, "action": "up", "min": 1, "max": 4}}`)
+	request, err := http.NewRequest("PUT", byID, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means update failed", response.Code)
+	}
+	wh := decodeWebhook(t, response)
+	if wh.Name != "wh-name-updated" || wh.Driver != "scaleService" || wh.Id != "1" || wh.ScaleServiceConfig.ServiceID != "id" ||
+		wh.ScaleServiceConfig.Max != 4 || wh.ScaleServiceConfig.Type != "scaleService" {
+		t.Fatalf("Unexpected webhook: %#v", wh)
+	}
+	if wh.URL != created.URL {
+		t.Fatalf("URL changed on update. Expected %v, Actual %v", created.URL, wh.URL)
+	}
+
+	// Test changing the driver of the webhook
+	jsonStr = []byte(`{"driver":"serviceUpgrade","name":"wh-name-updated",
+		"serviceUpgradeConfig": {"serviceSelector": {"foo": "bar"}, "tag": "wh-tag", "batchSize": 1, "intervalMillis":2}}`)
+	request, err = http.NewRequest("PUT", byID, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 400 {
+		t.Fatalf("Driver of a webhook should not be changeable, got: %v", response.Code)
+	}
+
+	// Test updating with an invalid config
+	jsonStr = []byte(`{"scaleServiceConfig": {"serviceId": "id", "amount": 1, "action": "up", "min": -1, "max": 4}}`)
+	request, err = http.NewRequest("PUT", byID, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code == 200 {
+		t.Fatalf("Invalid min")
+	}
+
+	// Test the old execute URL still works
+	requestExecute, err := http.NewRequest("POST", created.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	handler := HandleError(schemas, r.Execute)
+	handler.ServeHTTP(response, requestExecute)
+	if response.Code != 200 {
+		t.Errorf("StatusCode %d means execute failed", response.Code)
+	}
+
+	deleteReceiver(t, wh.Id)
+}
+
+func TestWebhookDeactivateAndActivate(t *testing.T) {
+	wh := createReceiver(t, scaleServiceReceiver)
+	if wh.Actions["deactivate"] == "" {
+		t.Fatalf("Deactivate action not available on active webhook: %#v", wh.Actions)
+	}
+	executeURL := wh.URL
+	mockDriver := drivers.Drivers["scaleService"].(*MockServiceDriver)
+
+	// Test deactivating the webhook
+	request, err := http.NewRequest("POST", wh.Actions["deactivate"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means deactivate failed", response.Code)
+	}
+	wh = decodeWebhook(t, response)
+	if wh.State != "inactive" || wh.Actions["activate"] == "" {
+		t.Fatalf("Unexpected webhook after deactivate: %#v", wh)
+	}
+
+	// Test executing the deactivated webhook doesn't run the driver
+	executions := mockDriver.executions
+	requestExecute, err := http.NewRequest("POST", executeURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	handler := HandleError(schemas, r.Execute)
+	handler.ServeHTTP(response, requestExecute)
+	if response.Code != 200 {
+		t.Errorf("StatusCode %d means execute failed", response.Code)
+	}
+	if mockDriver.executions != executions {
+		t.Fatal("Driver executed for inactive webhook")
+	}
+
+	// Test activating the webhook again
+	request, err = http.NewRequest("POST", wh.Actions["activate"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means activate failed", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, requestExecute)
+	if response.Code != 200 {
+		t.Errorf("StatusCode %d means execute failed", response.Code)
+	}
+	if mockDriver.executions != executions+1 {
+		t.Fatal("Driver not executed for activated webhook")
+	}
+
+	deleteReceiver(t, wh.Id)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/rancher/go-rancher/api"
+	"github.com/rancher/webhook-service/drivers"
+	"github.com/rancher/webhook-service/model"
+)
+
+func (rh *RouteHandler) GetJob(w http.ResponseWriter, r *http.Request) (int, error) {
+	apiContext := api.GetApiContext(r)
+	vars := mux.Vars(r)
+	jobID := vars["id"]
+	logrus.Infof("Getting job %v", jobID)
+
+	projectID, errCode, err := getProjectID(r)
+	if err != nil {
+		return errCode, err
+	}
+
+	job := rh.Jobs.Get(jobID)
+	if job == nil || job.ProjectID() != projectID {
+		return 404, fmt.Errorf("Job not found")
+	}
+
+	apiContext.WriteResource(newJob(apiContext, job))
+	return 200, nil
+}
+
+func newJob(context *api.ApiContext, job *drivers.Job) *model.Job {
+	resource := job.Resource()
+	query := "?projectId=" + job.ProjectID()
+	resource.Links = map[string]string{
+		"self":     context.UrlBuilder.ReferenceByIdLink("job", resource.Id) + query,
+		"receiver": context.UrlBuilder.ReferenceByIdLink("receiver", resource.ReceiverID) + query,
+	}
+	return &resource
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestWebhookJob(t *testing.T) {
+	wh := createReceiver(t, scaleServiceReceiver)
+
+	execute := func(url string, expectedCode int) *model.Job {
+		request, err := http.NewRequest("POST", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := httptest.NewRecorder()
+		handler := HandleError(schemas, r.Execute)
+		handler.ServeHTTP(response, request)
+		if response.Code != expectedCode {
+			t.Fatalf("StatusCode %d, expected %d", response.Code, expectedCode)
+		}
+		resp, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		job := &model.Job{}
+		err = json.Unmarshal(resp, job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Id == "" || job.ReceiverID != "1" || job.Driver != "scaleService" {
+			t.Fatalf("Unexpected job: %#v", job)
+		}
+		if !strings.Contains(job.Links["self"], "/v1-webhooks/jobs/"+job.Id+"?projectId=1a1") {
+			t.Fatalf("Bad job self URL: %v", job.Links["self"])
+		}
+		return job
+	}
+
+	// Test a synchronous execution returns the finished job
+	job := execute(wh.URL, 200)
+	if job.State != "succeeded" || job.Finished == "" {
+		t.Fatalf("Unexpected job: %#v", job)
+	}
+
+	// Test an asynchronous execution can be polled
+	job = execute(wh.URL+"&async=true", 202)
+	<-r.Jobs.Get(job.Id).Done()
+
+	request, err := http.NewRequest("GET", job.Links["self"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means get job failed", response.Code)
+	}
+	resp, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	polled := &model.Job{}
+	err = json.Unmarshal(resp, polled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polled.Id != job.Id || polled.State != "succeeded" {
+		t.Fatalf("Unexpected job: %#v", polled)
+	}
+
+	// Test jobs are not visible to other projects
+	request, err = http.NewRequest("GET", fmt.Sprintf("%s/v1-webhooks/jobs/%s?projectId=1a2", server.URL, job.Id), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 404 {
+		t.Fatalf("StatusCode %d, job of another project should not be found", response.Code)
+	}
+
+	deleteReceiver(t, wh.Id)
+}
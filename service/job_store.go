@@ -0,0 +1,64 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/dchest/uniuri"
+	"github.com/rancher/webhook-service/drivers"
+)
+
+const DefaultJobHistorySize = 1000
+
+// JobStore keeps the jobs of recent executions in memory so that they can be polled
+type JobStore struct {
+	sync.Mutex
+	size  int
+	jobs  map[string]*drivers.Job
+	order []string
+}
+
+func NewJobStore(size int) *JobStore {
+	if size <= 0 {
+		size = DefaultJobHistorySize
+	}
+	return &JobStore{
+		size: size,
+		jobs: map[string]*drivers.Job{},
+	}
+}
+
+func (s *JobStore) New(projectID string, receiverID string, driver string) *drivers.Job {
+	s.Lock()
+	defer s.Unlock()
+
+	job := drivers.NewJob(uniuri.NewLen(20), projectID, receiverID, driver)
+	s.jobs[job.ID()] = job
+	s.order = append(s.order, job.ID())
+	s.evict()
+	return job
+}
+
+func (s *JobStore) Get(id string) *drivers.Job {
+	s.Lock()
+	defer s.Unlock()
+	return s.jobs[id]
+}
+
+// evict drops the oldest finished jobs once the store is over its size, running jobs are always kept
+func (s *JobStore) evict() {
+	excess := len(s.order) - s.size
+	if excess <= 0 {
+		return
+	}
+
+	order := s.order[:0]
+	for _, id := range s.order {
+		if excess > 0 && !s.jobs[id].Running() {
+			delete(s.jobs, id)
+			excess--
+			continue
+		}
+		order = append(order, id)
+	}
+	s.order = order
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mitchellh/mapstructure"
+	v1client "github.com/rancher/go-rancher/client"
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/drivers"
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestWebhookCreateAndExecuteRollbackService(t *testing.T) {
+	// Test creating a webhook
+	constructURL := fmt.Sprintf("%s/v1-webhooks/receivers?projectId=1a1", server.URL)
+	jsonStr := []byte(`{"driver":"rollbackService","name":"wh-name",
+		"rollbackServiceConfig": {"serviceSelector": {"foo": "bar"}}}`)
+	request, err := http.NewRequest("POST", constructURL, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	handler := HandleError(schemas, r.ConstructPayload)
+	handler.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means ConstructPayloadTest failed", response.Code)
+	}
+	resp, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh := &model.Webhook{}
+	err = json.Unmarshal(resp, wh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wh.Name != "wh-name" || wh.Driver != "rollbackService" || wh.Id != "1" || wh.URL == "" ||
+		wh.RollbackServiceConfig.ServiceSelector["foo"] != "bar" || wh.RollbackServiceConfig.Type != "rollbackService" {
+		t.Fatalf("Unexpected webhook: %#v", wh)
+	}
+	if !strings.HasSuffix(wh.Links["self"], "/v1-webhooks/receivers/1?projectId=1a1") {
+		t.Fatalf("Bad self URL: %v", wh.Links["self"])
+	}
+
+	// Test getting the created webhook by id
+	byID := fmt.Sprintf("%s/v1-webhooks/receivers/1?projectId=1a1", server.URL)
+	request, err = http.NewRequest("GET", byID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means get failed", response.Code)
+	}
+	resp, err = ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh = &model.Webhook{}
+	err = json.Unmarshal(resp, wh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wh.Driver != "rollbackService" || wh.RollbackServiceConfig.ServiceSelector["foo"] != "bar" ||
+		wh.RollbackServiceConfig.Type != "rollbackService" {
+		t.Fatalf("Unexpected webhook: %#v", wh)
+	}
+
+	// Test executing the webhook
+	requestExecute, err := http.NewRequest("POST", wh.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	handler = HandleError(schemas, r.Execute)
+	handler.ServeHTTP(response, requestExecute)
+	if response.Code != 200 {
+		t.Errorf("StatusCode %d means execute failed", response.Code)
+	}
+
+	// Delete
+	request, err = http.NewRequest("DELETE", byID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 204 {
+		t.Fatalf("StatusCode %d means delete failed", response.Code)
+	}
+}
+
+func TestWebhookCreateInvalidRollbackService(t *testing.T) {
+	constructURL := fmt.Sprintf("%s/v1-webhooks/receivers?projectId=1a1", server.URL)
+	configs := []string{
+		`{}`,
+		`{"serviceId": "1s1", "serviceSelector": {"foo": "bar"}}`,
+		`{"serviceSelector": {"foo": "baz"}}`,
+	}
+	for _, config := range configs {
+		jsonStr := []byte(`{"driver":"rollbackService","name":"wh-name", "rollbackServiceConfig": ` + config + `}`)
+		request, err := http.NewRequest("POST", constructURL, bytes.NewBuffer(jsonStr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		handler := HandleError(schemas, r.ConstructPayload)
+		handler.ServeHTTP(response, request)
+		if response.Code != 400 {
+			t.Fatalf("StatusCode %d, config %s should be rejected", response.Code, config)
+		}
+	}
+}
+
+type MockRollbackServiceDriver struct {
+	expectedConfig model.RollbackService
+}
+
+func (s *MockRollbackServiceDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
+	config := &model.RollbackService{}
+	err := mapstructure.Decode(conf, config)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Couldn't unmarshal config: %v", err)
+	}
+
+	if config.ServiceSelector["foo"] != s.expectedConfig.ServiceSelector["foo"] {
+		return 500, fmt.Errorf("ServiceSelector. Expected %v, Actual %v", s.expectedConfig.ServiceSelector, config.ServiceSelector)
+	}
+
+	logrus.Infof("Execute of mock rollbackService driver")
+	return 0, nil
+}
+
+func (s *MockRollbackServiceDriver) ValidatePayload(conf interface{}, apiClient *client.RancherClient) (int, error) {
+	config, ok := conf.(model.RollbackService)
+	if !ok {
+		return http.StatusInternalServerError, fmt.Errorf("Can't process config")
+	}
+
+	if config.ServiceID != s.expectedConfig.ServiceID {
+		return 400, fmt.Errorf("ServiceID. Expected %v, Actual %v", s.expectedConfig.ServiceID, config.ServiceID)
+	}
+
+	if config.ServiceSelector["foo"] != s.expectedConfig.ServiceSelector["foo"] {
+		return 400, fmt.Errorf("ServiceSelector. Expected %v, Actual %v", s.expectedConfig.ServiceSelector, config.ServiceSelector)
+	}
+
+	logrus.Infof("Validate payload of mock rollbackService driver")
+	return 0, nil
+}
+
+func (s *MockRollbackServiceDriver) GetDriverConfigResource() interface{} {
+	return model.RollbackService{}
+}
+
+func (s *MockRollbackServiceDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Schema {
+	return schema
+}
+
+func (s *MockRollbackServiceDriver) ConvertToConfigAndSetOnWebhook(conf interface{}, webhook *model.Webhook) error {
+	rs := &drivers.RollbackServiceDriver{}
+	return rs.ConvertToConfigAndSetOnWebhook(conf, webhook)
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/dchest/uniuri"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/rancher/go-rancher/api"
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/drivers"
+	"github.com/rancher/webhook-service/model"
+)
+
+// previousKeyKind is the kind of the genericObjects keeping a rotated key valid during its grace period.
+// Storing them as their own objects lets executions look them up with the same key filter used for receivers
+const previousKeyKind = "webhookReceiverKey"
+
+func (rh *RouteHandler) RotateWebhookKey(w http.ResponseWriter, r *http.Request) (int, error) {
+	apiContext := api.GetApiContext(r)
+	vars := mux.Vars(r)
+	webhookID := vars["id"]
+	logrus.Infof("Rotating key of webhook %v", webhookID)
+
+	requestBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return 500, err
+	}
+
+	input := &model.RotateKeyInput{}
+	if len(requestBytes) > 0 {
+		if err := json.Unmarshal(requestBytes, input); err != nil {
+			return 400, errors.Wrap(err, "Bad request body")
+		}
+	}
+
+	if input.GracePeriodSeconds < 0 {
+		return 400, fmt.Errorf("Invalid grace period: %v", input.GracePeriodSeconds)
+	}
+
+	projectID, errCode, err := getProjectID(r)
+	if err != nil {
+		return errCode, err
+	}
+	apiClient, err := rh.ClientFactory.GetClient(projectID)
+	if err != nil {
+		return 500, err
+	}
+	obj, err := apiClient.GenericObject.ById(webhookID)
+	if err != nil {
+		return 500, err
+	}
+
+	if obj == nil {
+		return 404, fmt.Errorf("Webhook not found")
+	}
+
+	webhook, err := rh.convertToWebhookGenericObject(*obj)
+	if err != nil {
+		return 500, err
+	}
+
+	driver := drivers.GetDriver(webhook.Driver)
+	if driver == nil {
+		return 400, fmt.Errorf("Can't find driver %v", webhook.Driver)
+	}
+
+	now := time.Now()
+	if err := prunePreviousKeys(apiClient, webhookID, now); err != nil {
+		return 500, err
+	}
+	if input.GracePeriodSeconds > 0 {
+		expires := now.Add(time.Duration(input.GracePeriodSeconds) * time.Second)
+		if err := savePreviousKey(apiClient, webhookID, obj.Key, expires); err != nil {
+			return 500, err
+		}
+	}
+
+	uuid := uniuri.NewLen(40)
+	url := getExecuteURL(apiContext, uuid, projectID)
+
+	resourceData := map[string]interface{}{}
+	for k, v := range obj.ResourceData {
+		resourceData[k] = v
+	}
+	resourceData["url"] = url
+
+	if _, err = apiClient.GenericObject.Update(obj, &client.GenericObject{
+		Key:          uuid,
+		ResourceData: resourceData,
+	}); err != nil {
+		return 500, fmt.Errorf("Failed to update webhook: %v", err)
+	}
+
+	respWebhook, err := newWebhook(apiContext, url, webhook.ID, webhook.Driver, webhook.Name,
+		webhook.Config, driver, webhook.State, webhook.Filters, r)
+	if err != nil {
+		return 500, errors.Wrap(err, "Unable to create webhook response")
+	}
+
+	apiContext.WriteResource(respWebhook)
+	return 200, nil
+}
+
+func getExecuteURL(apiContext *api.ApiContext, uuid string, projectID string) string {
+	url := apiContext.UrlBuilder.Version("v1-webhooks")
+	return url + "/endpoint?key=" + uuid + "&projectId=" + projectID
+}
+
+// savePreviousKey keeps key valid for the receiver until expires
+func savePreviousKey(apiClient *client.RancherClient, receiverID string, key string, expires time.Time) error {
+	_, err := apiClient.GenericObject.Create(&client.GenericObject{
+		Name: receiverID,
+		Key:  key,
+		Kind: previousKeyKind,
+		ResourceData: map[string]interface{}{
+			"receiverId": receiverID,
+			"expires":    expires.UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to save previous key: %v", err)
+	}
+	return nil
+}
+
+// prunePreviousKeys deletes the previous keys of the receiver that expired before now, a zero now deletes all of them
+func prunePreviousKeys(apiClient *client.RancherClient, receiverID string, now time.Time) error {
+	filters := make(map[string]interface{})
+	filters["kind"] = previousKeyKind
+	filters["name"] = receiverID
+	keys, err := apiClient.GenericObject.List(&client.ListOpts{
+		Filters: filters,
+	})
+	if err != nil {
+		return fmt.Errorf("Error %v listing previous keys", err)
+	}
+	for i, key := range keys.Data {
+		if key.Kind != previousKeyKind || (!now.IsZero() && isValidPreviousKey(key, now)) {
+			continue
+		}
+		if err := apiClient.GenericObject.Delete(&keys.Data[i]); err != nil {
+			return fmt.Errorf("Error %v deleting previous key", err)
+		}
+	}
+	return nil
+}
+
+func isValidPreviousKey(key client.GenericObject, now time.Time) bool {
+	expiresData, _ := key.ResourceData["expires"].(string)
+	expires, err := time.Parse(time.RFC3339, expiresData)
+	if err != nil {
+		return false
+	}
+	return now.Before(expires)
+}
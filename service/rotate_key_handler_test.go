@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/go-rancher/v2"
+)
+
+func TestWebhookRotateKey(t *testing.T) {
+	wh := createReceiver(t, scaleServiceReceiver)
+	originalURL := wh.URL
+	rotateURL := wh.Actions["rotateKey"]
+	if rotateURL == "" {
+		t.Fatalf("RotateKey action not available on webhook: %#v", wh.Actions)
+	}
+
+	rotate := func(body string) string {
+		request, err := http.NewRequest("POST", rotateURL, bytes.NewBuffer([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		if response.Code != 200 {
+			t.Fatalf("StatusCode %d means rotateKey failed", response.Code)
+		}
+		return decodeWebhook(t, response).URL
+	}
+
+	execute := func(url string) int {
+		request, err := http.NewRequest("POST", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := httptest.NewRecorder()
+		handler := HandleError(schemas, r.Execute)
+		handler.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	// Test rotating without a grace period revokes the old URL
+	rotatedURL := rotate("")
+	if rotatedURL == originalURL || rotatedURL == "" {
+		t.Fatalf("URL not rotated: %v", rotatedURL)
+	}
+	if code := execute(originalURL); code != 403 {
+		t.Fatalf("StatusCode %d, old key should be revoked", code)
+	}
+	if code := execute(rotatedURL); code != 200 {
+		t.Fatalf("StatusCode %d means execute with rotated key failed", code)
+	}
+
+	// Test rotating with a grace period keeps the old URL valid
+	gracedURL := rotate(`{"gracePeriodSeconds": 60}`)
+	if gracedURL == rotatedURL {
+		t.Fatalf("URL not rotated: %v", gracedURL)
+	}
+	if code := execute(rotatedURL); code != 200 {
+		t.Fatalf("StatusCode %d, old key should be valid during grace period", code)
+	}
+	if code := execute(gracedURL); code != 200 {
+		t.Fatalf("StatusCode %d means execute with rotated key failed", code)
+	}
+
+	// Test expired keys are revoked and pruned
+	mock := r.ClientFactory.(*MockRancherClientFactory).mw
+	previousKeys := func() []*client.GenericObject {
+		keys := []*client.GenericObject{}
+		for _, obj := range mock.created {
+			if obj.Kind == previousKeyKind {
+				keys = append(keys, obj)
+			}
+		}
+		return keys
+	}
+	if keys := previousKeys(); len(keys) != 1 {
+		t.Fatalf("Expected one previous key, got %d", len(keys))
+	}
+	previousKeys()[0].ResourceData["expires"] = time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+	if code := execute(rotatedURL); code != 403 {
+		t.Fatalf("StatusCode %d, expired key should be revoked", code)
+	}
+	if keys := previousKeys(); len(keys) != 0 {
+		t.Fatalf("Expected expired key to be pruned, got %d keys", len(keys))
+	}
+
+	// Test deleting the webhook deletes its previous keys
+	rotate(`{"gracePeriodSeconds": 60}`)
+	deleteReceiver(t, wh.Id)
+	if keys := previousKeys(); len(keys) != 0 {
+		t.Fatalf("Expected previous keys to be deleted with the webhook, got %d keys", len(keys))
+	}
+}
@@ -44,9 +44,17 @@ type RouteHandler struct {
 	ClientFactory RancherClientFactory
 	PrivateKey    *rsa.PrivateKey
 	PublicKey     *rsa.PublicKey
+	Executions    *ExecutionLog
+	Jobs          *JobStore
 }
 
 func NewRouter(r *RouteHandler) *mux.Router {
+	if r.Executions == nil {
+		r.Executions = NewExecutionLog(DefaultExecutionHistorySize)
+	}
+	if r.Jobs == nil {
+		r.Jobs = NewJobStore(DefaultJobHistorySize)
+	}
 	schemas = driverSchemas()
 	router := mux.NewRouter().StrictSlash(false)
 	f := HandleError
@@ -69,9 +77,27 @@ func NewRouter(r *RouteHandler) *mux.Router {
 	router.Methods("GET").Path("/v1-webhooks/receivers/{id}").Handler(f(schemas, r.GetWebhook))
 	router.Methods("GET").Path("/v1-webhooks/receivers/{id}/").Handler(f(schemas, r.GetWebhook))
 
+	router.Methods("POST").Path("/v1-webhooks/receivers/{id}").Queries("action", "activate").Handler(f(schemas, r.ActivateWebhook))
+	router.Methods("POST").Path("/v1-webhooks/receivers/{id}/").Queries("action", "activate").Handler(f(schemas, r.ActivateWebhook))
+
+	router.Methods("POST").Path("/v1-webhooks/receivers/{id}").Queries("action", "deactivate").Handler(f(schemas, r.DeactivateWebhook))
+	router.Methods("POST").Path("/v1-webhooks/receivers/{id}/").Queries("action", "deactivate").Handler(f(schemas, r.DeactivateWebhook))
+
+	router.Methods("POST").Path("/v1-webhooks/receivers/{id}").Queries("action", "rotateKey").Handler(f(schemas, r.RotateWebhookKey))
+	router.Methods("POST").Path("/v1-webhooks/receivers/{id}/").Queries("action", "rotateKey").Handler(f(schemas, r.RotateWebhookKey))
+
+	router.Methods("PUT").Path("/v1-webhooks/receivers/{id}").Handler(f(schemas, r.UpdateWebhook))
+	router.Methods("PUT").Path("/v1-webhooks/receivers/{id}/").Handler(f(schemas, r.UpdateWebhook))
+
+	router.Methods("GET").Path("/v1-webhooks/receivers/{id}/executions").Handler(f(schemas, r.ListExecutions))
+	router.Methods("GET").Path("/v1-webhooks/receivers/{id}/executions/").Handler(f(schemas, r.ListExecutions))
+
 	router.Methods("DELETE").Path("/v1-webhooks/receivers/{id}").Handler(f(schemas, r.DeleteWebhook))
 	router.Methods("DELETE").Path("/v1-webhooks/receivers/{id}/").Handler(f(schemas, r.DeleteWebhook))
 
+	router.Methods("GET").Path("/v1-webhooks/jobs/{id}").Handler(f(schemas, r.GetJob))
+	router.Methods("GET").Path("/v1-webhooks/jobs/{id}/").Handler(f(schemas, r.GetJob))
+
 	router.Methods("POST").Path("/v1-webhooks/endpoint").Handler(f(schemas, r.Execute))
 	router.Methods("POST").Path("/v1-webhooks/endpoint/").Handler(f(schemas, r.Execute))
 
@@ -82,12 +108,51 @@ func driverSchemas() *v1client.Schemas {
 	schemas := &v1client.Schemas{}
 	webhook := schemas.AddType("receiver", model.Webhook{})
 	webhook.CollectionMethods = []string{"GET", "POST"}
-	webhook.ResourceMethods = []string{"GET", "DELETE"}
+	webhook.ResourceMethods = []string{"GET", "PUT", "DELETE"}
+	webhook.ResourceActions = map[string]v1client.Action{
+		"activate":   {Output: "receiver"},
+		"deactivate": {Output: "receiver"},
+		"rotateKey":  {Input: "rotateKeyInput", Output: "receiver"},
+	}
 
 	f := webhook.ResourceFields["name"]
 	f.Create = true
+	f.Update = true
 	webhook.ResourceFields["name"] = f
 
+	f = webhook.ResourceFields["secret"]
+	f.Type = "password"
+	f.Create = true
+	f.Update = true
+	webhook.ResourceFields["secret"] = f
+
+	f = webhook.ResourceFields["filters"]
+	f.Type = "array[filter]"
+	f.Create = true
+	f.Update = true
+	webhook.ResourceFields["filters"] = f
+
+	filter := schemas.AddType("filter", model.Filter{})
+	filter.CollectionMethods = []string{}
+	for k, f := range filter.ResourceFields {
+		f.Create = true
+		f.Update = true
+		filter.ResourceFields[k] = f
+	}
+	f = filter.ResourceFields["source"]
+	f.Type = "enum"
+	f.Options = filterSources
+	f.Default = filterSourceBody
+	filter.ResourceFields["source"] = f
+	f = filter.ResourceFields["operator"]
+	f.Type = "enum"
+	f.Options = filterOperators
+	f.Default = filterOperatorEquals
+	filter.ResourceFields["operator"] = f
+	f = filter.ResourceFields["path"]
+	f.Required = true
+	filter.ResourceFields["path"] = f
+
 	driverOptions := []string{}
 	for key, value := range drivers.Drivers {
 		webhookField := key + "Config"
@@ -95,6 +160,7 @@ func driverSchemas() *v1client.Schemas {
 			driverOptions = append(driverOptions, key)
 			field.Type = key
 			field.Create = true
+			field.Update = true
 			webhook.ResourceFields[webhookField] = field
 			driverConfig := schemas.AddType(key, value.GetDriverConfigResource())
 			driverConfig.CollectionMethods = []string{}
@@ -103,6 +169,7 @@ func driverSchemas() *v1client.Schemas {
 					f.Create = false
 				} else {
 					f.Create = true
+					f.Update = true
 				}
 				driverConfig.ResourceFields[k] = f
 			}
@@ -118,6 +185,25 @@ func driverSchemas() *v1client.Schemas {
 	f.Options = driverOptions
 	webhook.ResourceFields["driver"] = f
 
+	rotateKeyInput := schemas.AddType("rotateKeyInput", model.RotateKeyInput{})
+	rotateKeyInput.CollectionMethods = []string{}
+	f = rotateKeyInput.ResourceFields["gracePeriodSeconds"]
+	f.Create = true
+	f.Default = 0
+	rotateKeyInput.ResourceFields["gracePeriodSeconds"] = f
+
+	execution := schemas.AddType("receiverExecution", model.ReceiverExecution{})
+	execution.CollectionMethods = []string{}
+
+	job := schemas.AddType("job", model.Job{})
+	job.CollectionMethods = []string{}
+	job.ResourceMethods = []string{"GET"}
+	f = job.ResourceFields["results"]
+	f.Type = "array[jobResult]"
+	job.ResourceFields["results"] = f
+	jobResult := schemas.AddType("jobResult", model.JobResult{})
+	jobResult.CollectionMethods = []string{}
+
 	schemas.AddType("apiVersion", v1client.Resource{})
 	schemas.AddType("schema", v1client.Schema{})
 	schemas.AddType("error", model.ServerAPIError{})
@@ -269,6 +269,7 @@ func TestCreateWithInvalidDriver(t *testing.T) {
 
 type MockServiceDriver struct {
 	expectedConfig model.ScaleService
+	executions     int
 }
 
 func (s *MockServiceDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
@@ -290,6 +291,7 @@ func (s *MockServiceDriver) Execute(conf interface{}, apiClient *client.RancherC
 		return 500, fmt.Errorf("ServiceChange. Expected %v, Actual %v", s.expectedConfig.ScaleChange, config.ScaleChange)
 	}
 
+	s.executions++
 	logrus.Infof("Execute of mock scaleService driver")
 	return 0, nil
 }
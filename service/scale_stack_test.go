@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/mitchellh/mapstructure"
+	v1client "github.com/rancher/go-rancher/client"
+	"github.com/rancher/go-rancher/v2"
+	"github.com/rancher/webhook-service/drivers"
+	"github.com/rancher/webhook-service/model"
+)
+
+func TestWebhookCreateAndExecuteScaleStack(t *testing.T) {
+	// Test creating a webhook
+	constructURL := fmt.Sprintf("%s/v1-webhooks/receivers?projectId=1a1", server.URL)
+	jsonStr := []byte(`{"driver":"scaleStack","name":"wh-name",
+		"scaleStackConfig": {"stackId": "1st1", "amount": 50, "amountType": "percentage", "action": "up", "min": 1, "max": 4}}`)
+	request, err := http.NewRequest("POST", constructURL, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	handler := HandleError(schemas, r.ConstructPayload)
+	handler.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means ConstructPayloadTest failed", response.Code)
+	}
+	resp, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh := &model.Webhook{}
+	err = json.Unmarshal(resp, wh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wh.Name != "wh-name" || wh.Driver != "scaleStack" || wh.Id != "1" || wh.URL == "" ||
+		wh.ScaleStackConfig.StackID != "1st1" || wh.ScaleStackConfig.ScaleAction != "up" ||
+		wh.ScaleStackConfig.ScaleChange != 50 || wh.ScaleStackConfig.AmountType != "percentage" ||
+		wh.ScaleStackConfig.Min != 1 || wh.ScaleStackConfig.Max != 4 || wh.ScaleStackConfig.Type != "scaleStack" {
+		t.Fatalf("Unexpected webhook: %#v", wh)
+	}
+	if !strings.HasSuffix(wh.Links["self"], "/v1-webhooks/receivers/1?projectId=1a1") {
+		t.Fatalf("Bad self URL: %v", wh.Links["self"])
+	}
+
+	// Test getting the created webhook by id
+	byID := fmt.Sprintf("%s/v1-webhooks/receivers/1?projectId=1a1", server.URL)
+	request, err = http.NewRequest("GET", byID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means get failed", response.Code)
+	}
+	resp, err = ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh = &model.Webhook{}
+	err = json.Unmarshal(resp, wh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wh.Driver != "scaleStack" || wh.ScaleStackConfig.StackID != "1st1" || wh.ScaleStackConfig.Type != "scaleStack" {
+		t.Fatalf("Unexpected webhook: %#v", wh)
+	}
+
+	// Test executing the webhook
+	requestExecute, err := http.NewRequest("POST", wh.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	handler = HandleError(schemas, r.Execute)
+	handler.ServeHTTP(response, requestExecute)
+	if response.Code != 200 {
+		t.Errorf("StatusCode %d means execute failed", response.Code)
+	}
+
+	// Delete
+	request, err = http.NewRequest("DELETE", byID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 204 {
+		t.Fatalf("StatusCode %d means delete failed", response.Code)
+	}
+}
+
+func TestWebhookCreateInvalidScaleStack(t *testing.T) {
+	constructURL := fmt.Sprintf("%s/v1-webhooks/receivers?projectId=1a1", server.URL)
+	configs := []string{
+		`{"stackId": "1st1", "amount": 50, "amountType": "percentage", "action": "set", "min": 1, "max": 4}`,
+		`{"stackId": "1st1", "amount": 50, "amountType": "ratio", "action": "up", "min": 1, "max": 4}`,
+		`{"stackId": "", "amount": 50, "amountType": "percentage", "action": "up", "min": 1, "max": 4}`,
+		`{"stackId": "1st1", "amount": 50, "amountType": "percentage", "action": "up", "min": 4, "max": 1}`,
+	}
+	for _, config := range configs {
+		jsonStr := []byte(`{"driver":"scaleStack","name":"wh-name", "scaleStackConfig": ` + config + `}`)
+		request, err := http.NewRequest("POST", constructURL, bytes.NewBuffer(jsonStr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		handler := HandleError(schemas, r.ConstructPayload)
+		handler.ServeHTTP(response, request)
+		if response.Code != 400 {
+			t.Fatalf("StatusCode %d, config %s should be rejected", response.Code, config)
+		}
+	}
+}
+
+type MockStackDriver struct {
+	expectedConfig model.ScaleStack
+}
+
+func (s *MockStackDriver) Execute(conf interface{}, apiClient *client.RancherClient, request *http.Request) (int, error) {
+	config := &model.ScaleStack{}
+	err := mapstructure.Decode(conf, config)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Couldn't unmarshal config: %v", err)
+	}
+
+	if config.StackID != s.expectedConfig.StackID {
+		return 500, fmt.Errorf("StackID. Expected %v, Actual %v", s.expectedConfig.StackID, config.StackID)
+	}
+
+	if config.ScaleAction != s.expectedConfig.ScaleAction {
+		return 500, fmt.Errorf("ScaleAction. Expected %v, Actual %v", s.expectedConfig.ScaleAction, config.ScaleAction)
+	}
+
+	if config.ScaleChange != s.expectedConfig.ScaleChange {
+		return 500, fmt.Errorf("ScaleChange. Expected %v, Actual %v", s.expectedConfig.ScaleChange, config.ScaleChange)
+	}
+
+	logrus.Infof("Execute of mock scaleStack driver")
+	return 0, nil
+}
+
+func (s *MockStackDriver) ValidatePayload(conf interface{}, apiClient *client.RancherClient) (int, error) {
+	config, ok := conf.(model.ScaleStack)
+	if !ok {
+		return http.StatusInternalServerError, fmt.Errorf("Can't process config")
+	}
+
+	if config.StackID == "" {
+		return http.StatusBadRequest, fmt.Errorf("StackId not provided")
+	}
+
+	if config.ScaleAction != s.expectedConfig.ScaleAction {
+		return 400, fmt.Errorf("ScaleAction. Expected %v, Actual %v", s.expectedConfig.ScaleAction, config.ScaleAction)
+	}
+
+	if config.AmountType != s.expectedConfig.AmountType {
+		return 400, fmt.Errorf("AmountType. Expected %v, Actual %v", s.expectedConfig.AmountType, config.AmountType)
+	}
+
+	if config.Min != s.expectedConfig.Min {
+		return 400, fmt.Errorf("Min. Expected %v, Actual %v", s.expectedConfig.Min, config.Min)
+	}
+
+	if config.Max != s.expectedConfig.Max {
+		return 400, fmt.Errorf("Max. Expected %v, Actual %v", s.expectedConfig.Max, config.Max)
+	}
+
+	logrus.Infof("Validate payload of mock scaleStack driver")
+	return 0, nil
+}
+
+func (s *MockStackDriver) GetDriverConfigResource() interface{} {
+	return model.ScaleStack{}
+}
+
+func (s *MockStackDriver) CustomizeSchema(schema *v1client.Schema) *v1client.Schema {
+	return schema
+}
+
+func (s *MockStackDriver) ConvertToConfigAndSetOnWebhook(conf interface{}, webhook *model.Webhook) error {
+	ss := &drivers.ScaleStackDriver{}
+	return ss.ConvertToConfigAndSetOnWebhook(conf, webhook)
+}
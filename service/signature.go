@@ -0,0 +1,64 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/go-rancher/v2"
+)
+
+const (
+	githubSignatureHeader  = "X-Hub-Signature-256"
+	gitlabTokenHeader      = "X-Gitlab-Token"
+	genericSignatureHeader = "X-Signature"
+	sha256SignaturePrefix  = "sha256="
+)
+
+// verifySignature checks the request against the shared secret of the webhook, if one is configured
+func verifySignature(webhook *client.GenericObject, header http.Header, payload []byte) (int, error) {
+	secret, _ := webhook.ResourceData["secret"].(string)
+	if secret == "" {
+		return 0, nil
+	}
+
+	if signature := header.Get(githubSignatureHeader); signature != "" {
+		if !strings.HasPrefix(signature, sha256SignaturePrefix) ||
+			!validHMAC(secret, payload, strings.TrimPrefix(signature, sha256SignaturePrefix)) {
+			return 401, fmt.Errorf("Invalid %s signature", githubSignatureHeader)
+		}
+		return 0, nil
+	}
+
+	if token := header.Get(gitlabTokenHeader); token != "" {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return 401, fmt.Errorf("Invalid %s token", gitlabTokenHeader)
+		}
+		return 0, nil
+	}
+
+	if signature := header.Get(genericSignatureHeader); signature != "" {
+		if !validHMAC(secret, payload, strings.TrimPrefix(signature, sha256SignaturePrefix)) {
+			return 401, fmt.Errorf("Invalid %s signature", genericSignatureHeader)
+		}
+		return 0, nil
+	}
+
+	return 401, fmt.Errorf("Webhook requires a signature, supply one of %s, %s or %s headers",
+		githubSignatureHeader, gitlabTokenHeader, genericSignatureHeader)
+}
+
+// validHMAC compares the hex encoded signature with the HMAC-SHA256 of payload
+func validHMAC(secret string, payload []byte, signature string) bool {
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(actual, mac.Sum(nil))
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	response := postReceiver(t, `{"driver":"scaleService","name":"wh-name","secret":"s3cret",
+		"scaleServiceConfig": {"serviceId": "id", "amount": 1, "action": "up", "min": 1, "max": 4}}`)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d means ConstructPayloadTest failed", response.Code)
+	}
+	if strings.Contains(response.Body.String(), "s3cret") {
+		t.Fatal("Secret should not be returned")
+	}
+	wh := decodeWebhook(t, response)
+
+	body := `{"text":"payload"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		header       string
+		value        string
+		expectedCode int
+	}{
+		{"", "", 401},
+		{"X-Hub-Signature-256", "sha256=" + signature, 200},
+		{"X-Hub-Signature-256", signature, 401},
+		{"X-Hub-Signature-256", "sha256=00", 401},
+		{"X-Gitlab-Token", "s3cret", 200},
+		{"X-Gitlab-Token", "wrong", 401},
+		{"X-Signature", signature, 200},
+		{"X-Signature", "sha256=" + signature, 200},
+		{"X-Signature", "not-hex", 401},
+	}
+	for _, test := range tests {
+		request, err := http.NewRequest("POST", wh.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.header != "" {
+			request.Header.Set(test.header, test.value)
+		}
+		response := httptest.NewRecorder()
+		handler := HandleError(schemas, r.Execute)
+		handler.ServeHTTP(response, request)
+		if response.Code != test.expectedCode {
+			t.Fatalf("StatusCode %d for %s: %s, expected %d", response.Code, test.header, test.value, test.expectedCode)
+		}
+	}
+
+	// Test a form-encoded payload, GitHub's default content type, is verified as sent
+	form := "payload=" + url.QueryEscape(body)
+	mac = hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(form))
+	request, err := http.NewRequest("POST", wh.URL, strings.NewReader(form))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set(githubSignatureHeader, sha256SignaturePrefix+hex.EncodeToString(mac.Sum(nil)))
+	response = httptest.NewRecorder()
+	handler := HandleError(schemas, r.Execute)
+	handler.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Fatalf("StatusCode %d, form-encoded payload with a valid signature should execute", response.Code)
+	}
+
+	execute := func() int {
+		request, err := http.NewRequest("POST", wh.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := httptest.NewRecorder()
+		handler := HandleError(schemas, r.Execute)
+		handler.ServeHTTP(response, request)
+		return response.Code
+	}
+	update := func(secret string) {
+		byID := fmt.Sprintf("%s/v1-webhooks/receivers/1?projectId=1a1", server.URL)
+		request, err := http.NewRequest("PUT", byID, bytes.NewBufferString(`{"secret":`+secret+`,
+			"scaleServiceConfig": {"serviceId": "id", "amount": 1, "action": "up", "min": 1, "max": 4}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		if response.Code != 200 {
+			t.Fatalf("StatusCode %d means update failed", response.Code)
+		}
+	}
+
+	// Test an empty secret keeps the existing one
+	update(`""`)
+	if code := execute(); code != 401 {
+		t.Fatalf("StatusCode %d, secret should be kept when updated with an empty one", code)
+	}
+
+	// Test a null secret removes it
+	update(`null`)
+	if code := execute(); code != 200 {
+		t.Fatalf("StatusCode %d, secret should be removed when updated with null", code)
+	}
+
+	deleteReceiver(t, wh.Id)
+}